@@ -0,0 +1,75 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+)
+
+// CredentialsProvider authenticates outgoing requests (see WithAuth). It's called on each
+// request attempt, right before BeforeRequestHook hooks.
+type CredentialsProvider interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+}
+
+type CredentialsFunc func(ctx context.Context, req *http.Request) error
+
+func (f CredentialsFunc) Authenticate(ctx context.Context, req *http.Request) error {
+	return f(ctx, req)
+}
+
+// region - built-in providers
+
+// BearerToken sets "Authorization: Bearer <token>" header.
+func BearerToken(token string) CredentialsProvider {
+	return CredentialsFunc(func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// BasicAuth sets HTTP Basic authentication header.
+func BasicAuth(username, password string) CredentialsProvider {
+	return CredentialsFunc(func(_ context.Context, req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// APIKeyHeader sets API key header, e.g. APIKeyHeader("X-API-Key", key).
+func APIKeyHeader(header, key string) CredentialsProvider {
+	return CredentialsFunc(func(_ context.Context, req *http.Request) error {
+		req.Header.Set(header, key)
+		return nil
+	})
+}
+
+// APIKeyQuery sets API key query parameter, e.g. APIKeyQuery("api_key", key).
+func APIKeyQuery(param, key string) CredentialsProvider {
+	return CredentialsFunc(func(_ context.Context, req *http.Request) error {
+		q := req.URL.Query()
+		q.Set(param, key)
+		req.URL.RawQuery = q.Encode()
+		return nil
+	})
+}
+
+// DynamicCredentials calls provider function on each request to get credentials, e.g.
+// to read token rotated by secret manager:
+//
+//	DynamicCredentials(func(ctx context.Context) (CredentialsProvider, error) {
+//		token, err := os.ReadFile(tokenFile)
+//		return BearerToken(strings.TrimSpace(string(token))), err
+//	})
+//
+// Returned error fails the request.
+func DynamicCredentials(provider func(ctx context.Context) (CredentialsProvider, error)) CredentialsProvider {
+	return CredentialsFunc(func(ctx context.Context, req *http.Request) error {
+		credentials, err := provider(ctx)
+		if err != nil {
+			return err
+		}
+		return credentials.Authenticate(ctx, req)
+	})
+}
+
+// endregion
@@ -0,0 +1,64 @@
+package rc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider CredentialsProvider
+		check    func(t *testing.T, r *http.Request)
+	}{
+		{"bearer", BearerToken("token"), func(t *testing.T, r *http.Request) {
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		}},
+		{"basic", BasicAuth("user", "pass"), func(t *testing.T, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "user", user)
+			assert.Equal(t, "pass", pass)
+		}},
+		{"api key header", APIKeyHeader("X-API-Key", "key"), func(t *testing.T, r *http.Request) {
+			assert.Equal(t, "key", r.Header.Get("X-API-Key"))
+		}},
+		{"api key query", APIKeyQuery("api_key", "k&y"), func(t *testing.T, r *http.Request) {
+			assert.Equal(t, "k&y", r.URL.Query().Get("api_key"))
+			assert.Equal(t, "v", r.URL.Query().Get("q"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+				tt.check(t, r)
+			}, WithBasicOption(WithAuth(tt.provider)))
+			_, _, err := Get[string](context.Background(), c, WithQueryParam("q", "v"))
+			assert.NoError(t, err)
+		})
+	}
+}
+func TestAuthDynamic(t *testing.T) {
+	version := 0
+	errRotation := errors.New("secret is being rotated")
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, fmt.Sprintf("Bearer token-%d", version), r.Header.Get("Authorization"))
+	}, WithBasicOption(WithAuth(DynamicCredentials(func(context.Context) (CredentialsProvider, error) {
+		if version == 3 {
+			return nil, errRotation
+		}
+		return BearerToken(fmt.Sprintf("token-%d", version)), nil
+	}))))
+	for version = 1; version <= 2; version++ {
+		_, _, err := Get[string](context.Background(), c)
+		assert.NoError(t, err)
+	}
+	_, res, err := Get[string](context.Background(), c)
+	assert.ErrorIs(t, err, errRotation)
+	assert.Equal(t, 0, res.StatusCode)
+}
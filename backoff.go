@@ -0,0 +1,94 @@
+package rc
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// region - backoff policy
+
+// BackoffPolicy calculates delay before retry attempt. Attempt is 1 for the first
+// retry; previous is the delay used before previous retry (0 for the first one).
+type BackoffPolicy interface {
+	Delay(attempt int, previous time.Duration) time.Duration
+}
+
+type BackoffFunc func(attempt int, previous time.Duration) time.Duration
+
+func (f BackoffFunc) Delay(attempt int, previous time.Duration) time.Duration {
+	return f(attempt, previous)
+}
+
+// endregion
+// region - built-in policies
+
+// ConstantBackoff waits the same delay before each retry.
+func ConstantBackoff(delay time.Duration) BackoffPolicy {
+	return BackoffFunc(func(int, time.Duration) time.Duration {
+		return delay
+	})
+}
+
+// LinearBackoff waits initial + step * (attempt - 1).
+func LinearBackoff(initial, step time.Duration) BackoffPolicy {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		return saturate(float64(initial) + float64(step)*float64(attempt-1))
+	})
+}
+
+// ExponentialBackoff waits initial * multiplier ^ (attempt - 1).
+func ExponentialBackoff(initial time.Duration, multiplier float64) BackoffPolicy {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		return saturate(float64(initial) * math.Pow(multiplier, float64(attempt-1)))
+	})
+}
+
+// FullJitterBackoff waits random delay in [0, base * 2 ^ (attempt - 1)).
+// Should be combined with max delay cap (see WithRetryMaxDelay).
+func FullJitterBackoff(base time.Duration) BackoffPolicy {
+	exp := ExponentialBackoff(base, 2)
+	return BackoffFunc(func(attempt int, previous time.Duration) time.Duration {
+		return randomDuration(0, exp.Delay(attempt, previous))
+	})
+}
+
+// DecorrelatedJitterBackoff waits random delay in [base, previous * 3).
+// Should be combined with max delay cap (see WithRetryMaxDelay).
+func DecorrelatedJitterBackoff(base time.Duration) BackoffPolicy {
+	return BackoffFunc(func(_ int, previous time.Duration) time.Duration {
+		if previous < base {
+			previous = base
+		}
+		return randomDuration(base, saturate(float64(previous)*3))
+	})
+}
+
+// CappedBackoff limits delays of given policy with max.
+func CappedBackoff(policy BackoffPolicy, max time.Duration) BackoffPolicy {
+	return BackoffFunc(func(attempt int, previous time.Duration) time.Duration {
+		return min(policy.Delay(attempt, previous), max)
+	})
+}
+
+// endregion
+// region - util
+
+func saturate(value float64) time.Duration {
+	if value >= math.MaxInt64 || math.IsInf(value, 1) {
+		return time.Duration(math.MaxInt64)
+	}
+	if value <= 0 || math.IsNaN(value) {
+		return 0
+	}
+	return time.Duration(value)
+}
+
+func randomDuration(from, to time.Duration) time.Duration {
+	if to <= from {
+		return from
+	}
+	return from + rand.N(to-from)
+}
+
+// endregion
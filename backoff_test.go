@@ -0,0 +1,55 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDeterministic(t *testing.T) {
+	assert.Equal(t, time.Second, ConstantBackoff(time.Second).Delay(5, 0))
+
+	linear := LinearBackoff(time.Second, 2*time.Second)
+	assert.Equal(t, time.Second, linear.Delay(1, 0))
+	assert.Equal(t, 5*time.Second, linear.Delay(3, 0))
+
+	exp := ExponentialBackoff(100*time.Millisecond, 2)
+	assert.Equal(t, 100*time.Millisecond, exp.Delay(1, 0))
+	assert.Equal(t, 800*time.Millisecond, exp.Delay(4, 0))
+	assert.Equal(t, time.Duration(1<<63-1), exp.Delay(1000, 0))
+
+	capped := CappedBackoff(exp, time.Second)
+	assert.Equal(t, time.Second, capped.Delay(10, 0))
+}
+func TestBackoffJitter(t *testing.T) {
+	full := FullJitterBackoff(100 * time.Millisecond)
+	decorrelated := DecorrelatedJitterBackoff(100 * time.Millisecond)
+	previous := time.Duration(0)
+	for i := 1; i <= 20; i++ {
+		d := full.Delay(3, 0)
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.Less(t, d, 400*time.Millisecond)
+
+		d = decorrelated.Delay(i, previous)
+		assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+		assert.Less(t, d, max(previous, 100*time.Millisecond)*3)
+		previous = d
+	}
+}
+func TestRetryMaxElapsed(t *testing.T) {
+	handler, calls := failingHandler(100, http.StatusInternalServerError, nil)
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(-1)),
+		WithRetryOption(WithBackoff(ExponentialBackoff(10*time.Millisecond, 2))),
+		WithRetryOption(WithRetryMaxDelay(40*time.Millisecond)),
+		WithRetryOption(WithRetryMaxElapsed(100*time.Millisecond)),
+	)
+	_, res, err := Get[testItem](context.Background(), c)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	// delays: 10, 20, 40, 40 (cap) -> 110ms is over budget
+	assert.Equal(t, int32(4), calls.Load())
+}
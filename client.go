@@ -3,7 +3,7 @@ package rc
 import (
 	"context"
 	"errors"
-	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"time"
@@ -24,26 +24,71 @@ type Client interface {
 // endregion
 // region - client util
 
-func checkResponse(r *http.Response) (error, int) {
+func checkResponse(r *http.Response, attempt int) (error, int) {
 	if c := r.StatusCode; 200 <= c && c <= 299 {
 		return nil, r.StatusCode
 	}
 
-	if r.StatusCode == http.StatusTooManyRequests {
-		// TODO: parse response to get "Retry-After"
-		//       for now return -1 to make delay decision upstream
+	httpErr := newHTTPError(r, attempt)
+
+	if r.StatusCode == http.StatusTooManyRequests || r.StatusCode == http.StatusServiceUnavailable {
+		// if server did not tell how long to wait, return -1 to make delay decision upstream
+		delay, ok := retryAfter(r.Header, time.Now())
+		if !ok {
+			delay = time.Duration(-1) * time.Second
+		}
+		if r.StatusCode == http.StatusServiceUnavailable {
+			return ErrServiceUnavailable{
+				Delay: delay,
+				HTTP:  httpErr,
+			}, http.StatusServiceUnavailable
+		}
 		return ErrTooManyRequests{
-			Delay: time.Duration(-1) * time.Second,
+			Delay: delay,
+			HTTP:  httpErr,
 		}, http.StatusTooManyRequests
 	}
 
 	if r.StatusCode == http.StatusNotFound {
 		return ErrResourceNotFound{
-			r.Request.URL.String(),
+			Resource: r.Request.URL.String(),
+			HTTP:     httpErr,
 		}, http.StatusNotFound
 	}
 
-	return fmt.Errorf("status:[%d] %s", r.StatusCode, r.Status), r.StatusCode
+	return httpErr, r.StatusCode
+}
+
+// do executes request with given handler and decodes response into v (see Client.Do).
+func do(ctx context.Context, h Handler, req *http.Request, v interface{}) (*Response, int, error) {
+	resp, status, err := h(ctx, req)
+	if err != nil {
+		return resp, status, err
+	}
+	if err = resp.decode(v); err != nil {
+		return nil, status, err
+	}
+	return resp, status, nil
+}
+
+// layer implements Client methods which client layers delegate to wrapped client as is.
+type layer struct {
+	client Client
+}
+
+func (l layer) GetBaseURL() *url.URL {
+	return l.client.GetBaseURL()
+}
+func (l layer) NewRequest(options ...RequestOption) (*http.Request, error) {
+	return l.client.NewRequest(options...)
+}
+
+// closeClient closes client if it holds any resources (i.e. implements io.Closer)
+func closeClient(c Client) error {
+	if cl, ok := c.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
 }
 
 // endregion
@@ -53,11 +98,18 @@ func checkResponse(r *http.Response) (error, int) {
 
 type rcConfig struct {
 	basicOptions     []BasicClientOption
+	breakerOptions   []CircuitBreakerClientOption
+	bulkheadOptions  []BulkheadClientOption
 	throttleOptions  []ThrottleClientOption
+	oauth2Options    []OAuth2ClientOption
 	retryOptions     []RetryClientOption
 	basicAppender    []func(options []BasicClientOption) []BasicClientOption
+	breakerAppender  []func(options []CircuitBreakerClientOption) []CircuitBreakerClientOption
+	bulkheadAppender []func(options []BulkheadClientOption) []BulkheadClientOption
 	throttleAppender []func(options []ThrottleClientOption) []ThrottleClientOption
+	oauth2Appender   []func(options []OAuth2ClientOption) []OAuth2ClientOption
 	retryAppender    []func(options []RetryClientOption) []RetryClientOption
+	middlewares      []Middleware
 }
 
 // endregion
@@ -72,17 +124,41 @@ func WithBasicOption(option BasicClientOption) RestClientOption {
 		config.basicOptions = append(config.basicOptions, option)
 	}
 }
+func WithCircuitBreakerOption(option CircuitBreakerClientOption) RestClientOption {
+	return func(config *rcConfig) {
+		config.breakerOptions = append(config.breakerOptions, option)
+	}
+}
+func WithBulkheadOption(option BulkheadClientOption) RestClientOption {
+	return func(config *rcConfig) {
+		config.bulkheadOptions = append(config.bulkheadOptions, option)
+	}
+}
 func WithThrottleOption(option ThrottleClientOption) RestClientOption {
 	return func(config *rcConfig) {
 		config.throttleOptions = append(config.throttleOptions, option)
 	}
 }
+func WithOAuth2Option(option OAuth2ClientOption) RestClientOption {
+	return func(config *rcConfig) {
+		config.oauth2Options = append(config.oauth2Options, option)
+	}
+}
 func WithRetryOption(option RetryClientOption) RestClientOption {
 	return func(config *rcConfig) {
 		config.retryOptions = append(config.retryOptions, option)
 	}
 }
 
+// WithMiddleware adds middlewares wrapping client built from other options (the first
+// middleware is the outermost one). Built-in layers may be ordered arbitrarily by using
+// their middleware forms (ThrottleMiddleware, RetryMiddleware, ...) instead of options.
+func WithMiddleware(middlewares ...Middleware) RestClientOption {
+	return func(config *rcConfig) {
+		config.middlewares = append(config.middlewares, middlewares...)
+	}
+}
+
 // provide 'client options provider'
 
 func WithBasicAppender(appender func(options []BasicClientOption) []BasicClientOption) RestClientOption {
@@ -90,9 +166,24 @@ func WithBasicAppender(appender func(options []BasicClientOption) []BasicClientO
 		config.basicAppender = append(config.basicAppender, appender)
 	}
 }
+func WithCircuitBreakerAppender(appender func(options []CircuitBreakerClientOption) []CircuitBreakerClientOption) RestClientOption {
+	return func(config *rcConfig) {
+		config.breakerAppender = append(config.breakerAppender, appender)
+	}
+}
+func WithBulkheadAppender(appender func(options []BulkheadClientOption) []BulkheadClientOption) RestClientOption {
+	return func(config *rcConfig) {
+		config.bulkheadAppender = append(config.bulkheadAppender, appender)
+	}
+}
 func WithThrottleAppender(appender func(options []ThrottleClientOption) []ThrottleClientOption) RestClientOption {
 	return func(config *rcConfig) {
-		config.throttleAppender = append(config.throttleAppender)
+		config.throttleAppender = append(config.throttleAppender, appender)
+	}
+}
+func WithOAuth2Appender(appender func(options []OAuth2ClientOption) []OAuth2ClientOption) RestClientOption {
+	return func(config *rcConfig) {
+		config.oauth2Appender = append(config.oauth2Appender, appender)
 	}
 }
 func WithRetryAppender(appender func(options []RetryClientOption) []RetryClientOption) RestClientOption {
@@ -123,7 +214,25 @@ func CreateClient(options ...RestClientOption) (Client, error) {
 		return nil, err
 	}
 
-	if len(cfg.throttleOptions) > 0 || len(cfg.throttleOptions) > 0 {
+	if len(cfg.breakerOptions) > 0 || len(cfg.breakerAppender) > 0 {
+		for _, a := range cfg.breakerAppender {
+			cfg.breakerOptions = a(cfg.breakerOptions)
+		}
+		client, err = NewCircuitBreakerClient(client, cfg.breakerOptions...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.bulkheadOptions) > 0 || len(cfg.bulkheadAppender) > 0 {
+		for _, a := range cfg.bulkheadAppender {
+			cfg.bulkheadOptions = a(cfg.bulkheadOptions)
+		}
+		client, err = NewBulkheadClient(client, cfg.bulkheadOptions...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.throttleOptions) > 0 || len(cfg.throttleAppender) > 0 {
 		for _, a := range cfg.throttleAppender {
 			cfg.throttleOptions = a(cfg.throttleOptions)
 		}
@@ -132,6 +241,15 @@ func CreateClient(options ...RestClientOption) (Client, error) {
 			return nil, err
 		}
 	}
+	if len(cfg.oauth2Options) > 0 || len(cfg.oauth2Appender) > 0 {
+		for _, a := range cfg.oauth2Appender {
+			cfg.oauth2Options = a(cfg.oauth2Options)
+		}
+		client, err = NewOAuth2Client(client, cfg.oauth2Options...)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if len(cfg.retryOptions) > 0 || len(cfg.retryAppender) > 0 {
 		for _, a := range cfg.retryAppender {
 			cfg.retryOptions = a(cfg.retryOptions)
@@ -142,6 +260,10 @@ func CreateClient(options ...RestClientOption) (Client, error) {
 		}
 	}
 
+	if len(cfg.middlewares) > 0 {
+		client = Chain(client, cfg.middlewares...)
+	}
+
 	return client, err
 
 }
@@ -2,13 +2,11 @@ package rc
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"go.slink.ws/logging"
-	"io"
 	"net/http"
 	"net/url"
-	"time"
 )
 
 const (
@@ -16,10 +14,15 @@ const (
 )
 
 type BasicClient struct {
-	client    *http.Client
-	baseURL   *url.URL
-	userAgent string
-	logger    logging.Logger
+	client      *http.Client
+	baseURL     *url.URL
+	userAgent   string
+	codec       Codec
+	codecs      *codecRegistry
+	auth        CredentialsProvider
+	hooks       hooks
+	errorResult func() any
+	logger      logging.Logger
 }
 
 func NewBasicClient(options ...BasicClientOption) (Client, error) {
@@ -27,6 +30,8 @@ func NewBasicClient(options ...BasicClientOption) (Client, error) {
 	client := &BasicClient{
 		client:    http.DefaultClient,
 		userAgent: defaultUserAgent,
+		codec:     JSONCodec(),
+		codecs:    defaultCodecRegistry(),
 		logger:    logging.GetNoOpLogger(),
 	}
 
@@ -58,6 +63,7 @@ func (c *BasicClient) NewRequest(options ...RequestOption) (*http.Request, error
 		method:    http.MethodGet,
 		userAgent: &c.userAgent,
 		baseUrl:   c.GetBaseURL(),
+		codec:     c.codec,
 	}
 
 	for _, o := range options {
@@ -77,41 +83,8 @@ func (c *BasicClient) NewRequest(options ...RequestOption) (*http.Request, error
 }
 
 func (c *BasicClient) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, int, error) {
-
 	c.logger.Debug("do: %s %s", req.Method, req.URL)
-
-	resp, status, err := c.BareDo(ctx, req)
-	if err != nil {
-		return resp, status, err
-	}
-
-	switch v := v.(type) {
-	case nil:
-		return resp, status, nil
-	case io.Writer:
-		_, err = io.Copy(v, resp.Body)
-	default:
-		var b []byte
-		b, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, status, err
-		}
-		decErr := json.Unmarshal(b, &v)
-		if decErr == io.EOF {
-			decErr = nil // ignore EOF errors caused by empty response body
-		}
-		if decErr != nil {
-			err = decErr
-		}
-	}
-	if err != nil {
-		return nil, status, err
-	}
-	err = resp.Body.Close()
-	if err != nil {
-		return nil, status, err
-	}
-	return resp, status, err
+	return do(ctx, c.BareDo, req, v)
 }
 func (c *BasicClient) BareDo(ctx context.Context, req *http.Request) (*Response, int, error) {
 
@@ -133,42 +106,72 @@ func (c *BasicClient) BareDo(ctx context.Context, req *http.Request) (*Response,
 	//}
 	//fmt.Println("---------------------------------------------------------------------")
 
+	meta := getRequestMeta(req)
+	h := c.hooks.merge(meta.hooks)
+
+	// bind request to caller context (keeping request settings attached by builder);
+	// request is copied, so changes made by hooks do not leak into next attempts
+	req = req.Clone(context.WithValue(ctx, requestMetaKey{}, meta))
+
+	response, status, err := c.send(ctx, req, meta, h)
+	if err != nil {
+		h.error(ctx, req, status, err)
+	}
+	return response, status, err
+}
+
+func (c *BasicClient) send(ctx context.Context, req *http.Request, meta *requestMeta, h hooks) (*Response, int, error) {
+
+	if c.auth != nil {
+		if err := c.auth.Authenticate(ctx, req); err != nil {
+			// request was not sent
+			return nil, 0, err
+		}
+	}
+	if err := h.beforeRequest(ctx, req); err != nil {
+		// request was not sent
+		return nil, 0, err
+	}
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		// If we got an error, and the context has been canceled,
 		// the context's error is probably more useful.
 		select {
 		case <-ctx.Done():
-			return nil, http.StatusRequestTimeout, ctx.Err()
+			return nil, 0, ctx.Err()
 		default:
 		}
 
-		// returning *url.Error.
-		if e, ok := err.(*url.Error); ok {
-			return nil, http.StatusRequestURITooLong, e
-		}
+		// no response was received, so there is no status to report
+		return nil, 0, &TransportError{Err: err}
 
-		return nil, http.StatusBadRequest, err
+	}
 
+	response := &Response{
+		Response: resp,
+		codecs:   c.codecs,
+		codec:    meta.codec,
+		Attempt:  AttemptFromContext(ctx),
 	}
 
-	response := &Response{resp}
+	if err = h.afterResponse(ctx, response); err != nil {
+		_ = resp.Body.Close()
+		return nil, resp.StatusCode, err
+	}
 
 	var status int
 
-	err, status = checkResponse(resp)
+	err, status = checkResponse(resp, response.Attempt)
 	if err != nil {
+		var he *HTTPError
+		if errors.As(err, &he) {
+			c.decodeError(he, meta)
+		}
 		clErr := resp.Body.Close()
 		if clErr != nil {
 			return nil, status, fmt.Errorf("got some errors: \n%s \nand \n%s", err.Error(), clErr.Error())
 		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			// TODO: parse response to get "Retry-After"
-			//       for now return -1 to make delay decision upstream
-			return nil, status, ErrTooManyRequests{
-				Delay: time.Duration(-1) * time.Second,
-			}
-		}
 		return nil, status, err
 	}
 	return response, status, err
@@ -0,0 +1,306 @@
+package rc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.slink.ws/logging"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type ErrCircuitOpen struct {
+	Delay time.Duration
+}
+
+func (e ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("Circuit Open; Wait %s", e.Delay)
+}
+
+// region - circuit state
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// endregion
+// region - rolling window
+
+const windowBuckets = 10
+
+type windowBucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+// rollingWindow counts requests & failures over sliding time window
+type rollingWindow struct {
+	size    time.Duration
+	buckets [windowBuckets]windowBucket
+}
+
+func (w *rollingWindow) record(now time.Time, failed bool) {
+	width := max(w.size/windowBuckets, 1)
+	start := now.Truncate(width)
+	b := &w.buckets[int(start.UnixNano()/int64(width))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = windowBucket{start: start}
+	}
+	b.total++
+	if failed {
+		b.failures++
+	}
+}
+
+func (w *rollingWindow) counts(now time.Time) (total, failures int) {
+	for _, b := range w.buckets {
+		if now.Sub(b.start) < w.size {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
+
+func (w *rollingWindow) reset() {
+	w.buckets = [windowBuckets]windowBucket{}
+}
+
+// endregion
+// region - client
+
+// CircuitBreakerClient stops calling wrapped client after too many failures. While circuit
+// is open, requests fail with ErrCircuitOpen without touching the network; after open
+// duration, limited number of probe requests is let through (half-open state) to decide
+// whether to close circuit again.
+type CircuitBreakerClient struct {
+	layer
+	failureThreshold int
+	failureRatio     float64
+	window           time.Duration
+	minRequests      int
+	openDuration     time.Duration
+	halfOpenProbes   int
+	isFailure        func(status int, err error) bool
+	onStateChange    []func(from, to CircuitState)
+	clock            Clock
+	logger           logging.Logger
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  uint64
+	consecutive int
+	rolling     rollingWindow
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+func NewCircuitBreakerClient(client Client, options ...CircuitBreakerClientOption) (Client, error) {
+
+	c := &CircuitBreakerClient{
+		layer:            layer{client},
+		failureThreshold: 5,
+		openDuration:     30 * time.Second,
+		halfOpenProbes:   1,
+		isFailure:        DefaultCircuitFailure,
+		clock:            SystemClock(),
+		logger:           logging.GetNoOpLogger(),
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+	if c.halfOpenProbes <= 0 {
+		return nil, fmt.Errorf("invalid half-open probes number: %d", c.halfOpenProbes)
+	}
+	c.rolling.size = c.window
+
+	c.logger.Trace("new client")
+
+	return c, nil
+}
+
+// CircuitBreakerMiddleware returns circuit breaker layer as Middleware (see Chain).
+// Options are validated right away.
+func CircuitBreakerMiddleware(options ...CircuitBreakerClientOption) (Middleware, error) {
+	return layerMiddleware(NewCircuitBreakerClient, options)
+}
+
+// DefaultCircuitFailure treats network errors, timeouts and 5xx responses as failures.
+func DefaultCircuitFailure(status int, err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return isTransportError(err) || errors.Is(err, context.DeadlineExceeded) || status >= http.StatusInternalServerError
+}
+
+func (c *CircuitBreakerClient) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, int, error) {
+	c.logger.Trace("do: %s %s", req.Method, req.URL)
+	return do(ctx, c.BareDo, req, v)
+}
+
+func (c *CircuitBreakerClient) BareDo(ctx context.Context, req *http.Request) (*Response, int, error) {
+	c.logger.Trace("bare do: %s %s", req.Method, req.URL)
+
+	if ctx == nil {
+		return nil, http.StatusInternalServerError, ErrNonNilContext
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	generation, err := c.allow()
+	if err != nil {
+		c.logger.Debug("circuit breaker: %s", err)
+		return nil, 0, err // request was not sent
+	}
+	res, status, err := c.client.BareDo(ctx, req)
+	if errors.Is(err, context.Canceled) {
+		// caller gave up; outcome says nothing about upstream health
+		c.release(generation)
+		return res, status, err
+	}
+	c.record(generation, c.isFailure(status, err))
+	return res, status, err
+}
+
+// State returns current circuit state.
+func (c *CircuitBreakerClient) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == CircuitOpen && !c.clock.Now().Before(c.openedAt.Add(c.openDuration)) {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// Close closes wrapped client if it implements io.Closer.
+func (c *CircuitBreakerClient) Close() error {
+	c.logger.Trace("close")
+	return closeClient(c.client)
+}
+
+// allow checks whether request may pass; returns state generation request is executed in
+func (c *CircuitBreakerClient) allow() (uint64, error) {
+	c.mu.Lock()
+	var notify func()
+	defer func() {
+		c.mu.Unlock()
+		if notify != nil {
+			notify()
+		}
+	}()
+
+	now := c.clock.Now()
+	if c.state == CircuitOpen {
+		if wait := c.openedAt.Add(c.openDuration).Sub(now); wait > 0 {
+			return 0, ErrCircuitOpen{Delay: wait}
+		}
+		notify = c.transition(CircuitHalfOpen, now)
+	}
+	if c.state == CircuitHalfOpen {
+		if c.probes >= c.halfOpenProbes {
+			return 0, ErrCircuitOpen{}
+		}
+		c.probes++
+	}
+	return c.generation, nil
+}
+
+// record registers request outcome
+func (c *CircuitBreakerClient) record(generation uint64, failed bool) {
+	c.mu.Lock()
+	var notify func()
+	defer func() {
+		c.mu.Unlock()
+		if notify != nil {
+			notify()
+		}
+	}()
+
+	if generation != c.generation {
+		return // request was started in previous state
+	}
+	now := c.clock.Now()
+	switch c.state {
+	case CircuitClosed:
+		if failed {
+			c.consecutive++
+		} else {
+			c.consecutive = 0
+		}
+		if c.window > 0 {
+			c.rolling.record(now, failed)
+		}
+		if c.tripped(now) {
+			notify = c.transition(CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			notify = c.transition(CircuitOpen, now)
+			return
+		}
+		c.successes++
+		if c.successes >= c.halfOpenProbes {
+			notify = c.transition(CircuitClosed, now)
+		}
+	}
+}
+
+// release frees half-open probe slot taken by request, which outcome is unknown
+func (c *CircuitBreakerClient) release(generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation == c.generation && c.state == CircuitHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+func (c *CircuitBreakerClient) tripped(now time.Time) bool {
+	if c.failureRatio > 0 && c.window > 0 {
+		total, failures := c.rolling.counts(now)
+		return total >= c.minRequests && total > 0 && float64(failures)/float64(total) >= c.failureRatio
+	}
+	return c.failureThreshold > 0 && c.consecutive >= c.failureThreshold
+}
+
+// transition switches state (must be called with lock held); returns callbacks notification
+func (c *CircuitBreakerClient) transition(to CircuitState, now time.Time) func() {
+	from := c.state
+	c.state = to
+	c.generation++
+	c.consecutive = 0
+	c.probes = 0
+	c.successes = 0
+	c.rolling.reset()
+	if to == CircuitOpen {
+		c.openedAt = now
+	}
+	c.logger.Debug("circuit breaker: %s -> %s", from, to)
+	callbacks := c.onStateChange
+	return func() {
+		for _, cb := range callbacks {
+			cb(from, to)
+		}
+	}
+}
+
+// endregion
@@ -0,0 +1,171 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerConsecutive(t *testing.T) {
+	var fail atomic.Bool
+	var calls atomic.Int32
+	fail.Store(true)
+	clock := newManualClock()
+	var mu sync.Mutex
+	var transitions []string
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	},
+		WithCircuitBreakerOption(WithFailureThreshold(3)),
+		WithCircuitBreakerOption(WithOpenDuration(time.Minute)),
+		WithCircuitBreakerOption(WithHalfOpenProbes(2)),
+		WithCircuitBreakerOption(WithBreakerClock(clock)),
+		WithCircuitBreakerOption(WithStateChangeCallback(func(from, to CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, from.String()+">"+to.String())
+		})),
+	)
+	get := func() error {
+		_, _, err := Get[testItem](context.Background(), c)
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		assert.Error(t, get())
+	}
+	var open ErrCircuitOpen
+	_, res, err := Get[testItem](context.Background(), c)
+	if assert.ErrorAs(t, err, &open) {
+		assert.Equal(t, time.Minute, open.Delay)
+	}
+	assert.Equal(t, 0, res.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+
+	// failed probe opens circuit again
+	clock.Advance(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, c.(*CircuitBreakerClient).State())
+	assert.Error(t, get())
+	assert.ErrorAs(t, get(), &ErrCircuitOpen{})
+	assert.Equal(t, int32(4), calls.Load())
+
+	// successful probes close it
+	clock.Advance(time.Minute)
+	fail.Store(false)
+	assert.NoError(t, get())
+	assert.NoError(t, get())
+	assert.Equal(t, CircuitClosed, c.(*CircuitBreakerClient).State())
+	assert.NoError(t, get())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed",
+	}, transitions)
+}
+func TestCircuitBreakerRatio(t *testing.T) {
+	var calls atomic.Int32
+	clock := newManualClock()
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// every other request fails
+		if calls.Add(1)%2 == 0 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	},
+		WithCircuitBreakerOption(WithFailureRatio(0.5, 10*time.Second, 6)),
+		WithCircuitBreakerOption(WithBreakerClock(clock)),
+	)
+	for i := 0; i < 5; i++ {
+		_, _, _ = Get[testItem](context.Background(), c)
+		clock.Advance(time.Second)
+	}
+	assert.Equal(t, CircuitClosed, c.(*CircuitBreakerClient).State())
+	_, _, _ = Get[testItem](context.Background(), c)
+	assert.Equal(t, CircuitOpen, c.(*CircuitBreakerClient).State())
+}
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}, WithCircuitBreakerOption(WithFailureThreshold(1)))
+	for i := 0; i < 3; i++ {
+		_, _, err := Get[testItem](context.Background(), c)
+		assert.Error(t, err)
+		assert.NotErrorAs(t, err, &ErrCircuitOpen{})
+	}
+}
+func TestCircuitBreakerTimeouts(t *testing.T) {
+	var fail atomic.Bool
+	clock := newManualClock()
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		<-r.Context().Done() // slow upstream
+	},
+		WithCircuitBreakerOption(WithFailureThreshold(2)),
+		WithCircuitBreakerOption(WithOpenDuration(time.Minute)),
+		WithCircuitBreakerOption(WithBreakerClock(clock)),
+	)
+	get := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, _, err := Get[testItem](ctx, c)
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, get(), context.DeadlineExceeded)
+	}
+	assert.Equal(t, CircuitOpen, c.(*CircuitBreakerClient).State())
+
+	// timed out probe does not close circuit
+	clock.Advance(time.Minute)
+	assert.ErrorIs(t, get(), context.DeadlineExceeded)
+	assert.Equal(t, CircuitOpen, c.(*CircuitBreakerClient).State())
+}
+func TestCircuitBreakerCanceledProbe(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	started := make(chan struct{}, 1)
+	clock := newManualClock()
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		started <- struct{}{}
+		<-r.Context().Done()
+	},
+		WithCircuitBreakerOption(WithFailureThreshold(1)),
+		WithCircuitBreakerOption(WithOpenDuration(time.Minute)),
+		WithCircuitBreakerOption(WithBreakerClock(clock)),
+	)
+	_, _, err := Get[testItem](context.Background(), c)
+	assert.Error(t, err)
+	assert.Equal(t, CircuitOpen, c.(*CircuitBreakerClient).State())
+
+	// canceled probe is neither success nor failure
+	clock.Advance(time.Minute)
+	fail.Store(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, _, err = Get[testItem](ctx, c)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, CircuitHalfOpen, c.(*CircuitBreakerClient).State())
+
+	// probe slot is released
+	fail.Store(true)
+	_, _, err = Get[testItem](context.Background(), c)
+	assert.NotErrorAs(t, err, &ErrCircuitOpen{})
+	assert.Equal(t, CircuitOpen, c.(*CircuitBreakerClient).State())
+}
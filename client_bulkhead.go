@@ -0,0 +1,126 @@
+package rc
+
+import (
+	"context"
+	"fmt"
+	"go.slink.ws/logging"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+type ErrBulkheadFull struct {
+	MaxConcurrent int
+	QueueLength   int
+}
+
+func (e ErrBulkheadFull) Error() string {
+	return fmt.Sprintf("Bulkhead Full; %d request(s) in flight, %d queued", e.MaxConcurrent, e.QueueLength)
+}
+
+// BulkheadClient limits number of concurrent requests to wrapped client. Requests above
+// the limit wait in queue (if configured) or are rejected with ErrBulkheadFull.
+type BulkheadClient struct {
+	layer
+	maxConcurrent int
+	queueLength   int
+	queueTimeout  time.Duration
+	clock         Clock
+	logger        logging.Logger
+	slots         chan struct{}
+	queued        atomic.Int32
+}
+
+func NewBulkheadClient(client Client, options ...BulkheadClientOption) (Client, error) {
+
+	c := &BulkheadClient{
+		layer:         layer{client},
+		maxConcurrent: 10,
+		clock:         SystemClock(),
+		logger:        logging.GetNoOpLogger(),
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+	if c.maxConcurrent <= 0 {
+		return nil, fmt.Errorf("invalid max concurrent requests: %d", c.maxConcurrent)
+	}
+
+	c.slots = make(chan struct{}, c.maxConcurrent)
+	c.logger.Trace("new client")
+
+	return c, nil
+}
+
+// BulkheadMiddleware returns bulkhead layer as Middleware (see Chain).
+// Options are validated right away.
+func BulkheadMiddleware(options ...BulkheadClientOption) (Middleware, error) {
+	return layerMiddleware(NewBulkheadClient, options)
+}
+
+func (c *BulkheadClient) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, int, error) {
+	c.logger.Trace("do: %s %s", req.Method, req.URL)
+	return do(ctx, c.BareDo, req, v)
+}
+
+func (c *BulkheadClient) BareDo(ctx context.Context, req *http.Request) (*Response, int, error) {
+	c.logger.Trace("bare do: %s %s", req.Method, req.URL)
+
+	if ctx == nil {
+		return nil, http.StatusInternalServerError, ErrNonNilContext
+	}
+	if err := c.acquire(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, err
+		}
+		c.logger.Debug("bulkhead: request rejected")
+		return nil, 0, err // request was not sent
+	}
+	defer func() {
+		<-c.slots
+	}()
+	return c.client.BareDo(ctx, req)
+}
+
+// acquire takes free slot, waiting in queue if there is none
+func (c *BulkheadClient) acquire(ctx context.Context) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	full := ErrBulkheadFull{
+		MaxConcurrent: c.maxConcurrent,
+		QueueLength:   c.queueLength,
+	}
+	if c.queued.Add(1) > int32(c.queueLength) {
+		c.queued.Add(-1)
+		return full
+	}
+	defer c.queued.Add(-1)
+
+	var timeout <-chan time.Time
+	if c.queueTimeout > 0 {
+		timeout = c.clock.After(c.queueTimeout)
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout:
+		return full
+	}
+}
+
+// InFlight returns number of requests currently executed through the client.
+func (c *BulkheadClient) InFlight() int {
+	return len(c.slots)
+}
+
+// Close closes wrapped client if it implements io.Closer.
+func (c *BulkheadClient) Close() error {
+	c.logger.Trace("close")
+	return closeClient(c.client)
+}
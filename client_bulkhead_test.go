@@ -0,0 +1,123 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingHandler blocks requests until release is closed, tracking max concurrency
+func blockingHandler(release <-chan struct{}, current, peak *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+	}
+}
+
+func TestBulkheadLimitsConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	var current, peak atomic.Int32
+	c := createTestServerClient(t, blockingHandler(release, &current, &peak),
+		WithBulkheadOption(WithMaxConcurrent(2)),
+		WithBulkheadOption(WithQueueLength(10)),
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := Get[testItem](context.Background(), c)
+			assert.NoError(t, err)
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return current.Load() == 2
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(2), peak.Load())
+}
+func TestBulkheadRejects(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var current, peak atomic.Int32
+	c := createTestServerClient(t, blockingHandler(release, &current, &peak),
+		WithBulkheadOption(WithMaxConcurrent(1)),
+		WithBulkheadOption(WithQueueLength(1)),
+		WithBulkheadOption(WithQueueTimeout(time.Minute)),
+		WithBulkheadOption(WithBulkheadClock(newManualClock())), // queue timeout fires at once
+	)
+	go func() {
+		_, _, _ = Get[testItem](context.Background(), c)
+	}()
+	assert.Eventually(t, func() bool {
+		return current.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// waits in queue till timeout
+	_, res, err := Get[testItem](context.Background(), c)
+	assert.ErrorAs(t, err, &ErrBulkheadFull{})
+	assert.Equal(t, 0, res.StatusCode)
+
+}
+func TestBulkheadNoQueue(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var current, peak atomic.Int32
+	clock := &stoppedClock{}
+	c := createTestServerClient(t, blockingHandler(release, &current, &peak),
+		WithBulkheadOption(WithMaxConcurrent(1)),
+		WithBulkheadOption(WithQueueTimeout(time.Minute)),
+		WithBulkheadOption(WithBulkheadClock(clock)),
+	)
+	go func() {
+		_, _, _ = Get[testItem](context.Background(), c)
+	}()
+	assert.Eventually(t, func() bool {
+		return current.Load() == 1
+	}, time.Second, time.Millisecond)
+	_, _, err := Get[testItem](context.Background(), c)
+	assert.ErrorAs(t, err, &ErrBulkheadFull{})
+	assert.Equal(t, int32(0), clock.waits.Load()) // rejected without waiting
+}
+func TestBulkheadQueueContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var current, peak atomic.Int32
+	clock := &stoppedClock{}
+	c := createTestServerClient(t, blockingHandler(release, &current, &peak),
+		WithBulkheadOption(WithMaxConcurrent(1)),
+		WithBulkheadOption(WithQueueLength(1)),
+		WithBulkheadOption(WithQueueTimeout(time.Minute)),
+		WithBulkheadOption(WithBulkheadClock(clock)),
+	)
+	go func() {
+		_, _, _ = Get[testItem](context.Background(), c)
+	}()
+	assert.Eventually(t, func() bool {
+		return current.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for clock.waits.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	_, res, err := Get[testItem](ctx, c)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, res.StatusCode)
+}
@@ -0,0 +1,285 @@
+package rc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.slink.ws/logging"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2Token is access token obtained from token endpoint. Zero Expiry means token
+// does not expire (or server did not tell when it does).
+type OAuth2Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// OAuth2Error is returned when token endpoint refuses to issue token (RFC 6749, 5.2).
+// HTTP holds token endpoint response.
+type OAuth2Error struct {
+	Code        string
+	Description string
+	HTTP        *HTTPError
+}
+
+func (e *OAuth2Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth2: %s: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("oauth2: %s", e.Code)
+}
+func (e *OAuth2Error) Unwrap() error {
+	return unwrapHTTPError(e.HTTP)
+}
+
+// OAuth2Client authorizes requests with OAuth2 access token obtained with client
+// credentials or refresh token grant. Token is cached until shortly before its expiry
+// and is fetched once for all concurrent requests. If upstream responds with 401,
+// request is repeated once with fresh token.
+type OAuth2Client struct {
+	layer
+	tokenURL        string
+	grantType       string
+	clientID        string
+	clientSecret    string
+	refreshToken    string
+	scopes          []string
+	params          url.Values
+	credentialsBody bool
+	httpClient      *http.Client
+	expirySkew      time.Duration
+	fetchTimeout    time.Duration
+	clock           Clock
+	logger          logging.Logger
+
+	mu       sync.Mutex
+	token    *OAuth2Token
+	inflight *tokenCall
+}
+
+type tokenCall struct {
+	done  chan struct{}
+	token *OAuth2Token
+	err   error
+}
+
+func NewOAuth2Client(client Client, options ...OAuth2ClientOption) (Client, error) {
+
+	c := &OAuth2Client{
+		layer:        layer{client},
+		params:       url.Values{},
+		httpClient:   http.DefaultClient,
+		expirySkew:   30 * time.Second,
+		fetchTimeout: 30 * time.Second,
+		clock:        SystemClock(),
+		logger:       logging.GetNoOpLogger(),
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+	if c.tokenURL == "" || c.grantType == "" {
+		return nil, fmt.Errorf("oauth2 grant is not configured")
+	}
+	if c.grantType == "refresh_token" && c.refreshToken == "" {
+		return nil, fmt.Errorf("oauth2 refresh token is not set")
+	}
+
+	c.logger.Trace("new client")
+
+	return c, nil
+}
+
+// OAuth2Middleware returns OAuth2 layer as Middleware (see Chain).
+// Options are validated right away.
+func OAuth2Middleware(options ...OAuth2ClientOption) (Middleware, error) {
+	return layerMiddleware(NewOAuth2Client, options)
+}
+
+func (c *OAuth2Client) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, int, error) {
+	c.logger.Trace("do: %s %s", req.Method, req.URL)
+	return do(ctx, c.BareDo, req, v)
+}
+func (c *OAuth2Client) BareDo(ctx context.Context, req *http.Request) (*Response, int, error) {
+
+	c.logger.Trace("bare do: %s %s", req.Method, req.URL)
+
+	if ctx == nil {
+		return nil, http.StatusInternalServerError, ErrNonNilContext
+	}
+
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, status, err := c.client.BareDo(ctx, authorize(req.Clone(req.Context()), token))
+	if status != http.StatusUnauthorized {
+		return res, status, err
+	}
+
+	// token may be revoked before its expiry; retry once with fresh one
+	r, rwErr := rewindRequest(req)
+	if rwErr != nil {
+		c.logger.Debug("could not repeat unauthorized request: %s", rwErr)
+		return res, status, err
+	}
+	c.logger.Debug("unauthorized, refresh token")
+	if token, err = c.fetchToken(ctx, token.AccessToken); err != nil {
+		return nil, 0, err
+	}
+	return c.client.BareDo(ctx, authorize(r, token))
+}
+
+// Close closes wrapped client if it implements io.Closer.
+func (c *OAuth2Client) Close() error {
+	c.logger.Trace("close")
+	return closeClient(c.client)
+}
+
+// Token returns cached access token, or fetches new one if it's missing or expires soon.
+func (c *OAuth2Client) Token(ctx context.Context) (*OAuth2Token, error) {
+	return c.fetchToken(ctx, "")
+}
+
+// fetchToken returns valid cached token unless it's the stale one; otherwise it fetches
+// new token. Concurrent callers share single token request, which is detached from
+// their contexts, so that one canceled caller does not fail the others.
+func (c *OAuth2Client) fetchToken(ctx context.Context, stale string) (*OAuth2Token, error) {
+	c.mu.Lock()
+	if t := c.token; t != nil && t.AccessToken != stale && c.valid(t) {
+		c.mu.Unlock()
+		return t, nil
+	}
+	call := c.inflight
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		c.inflight = call
+		go c.fetch(call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *OAuth2Client) valid(t *OAuth2Token) bool {
+	return t.Expiry.IsZero() || c.clock.Now().Add(c.expirySkew).Before(t.Expiry)
+}
+
+func (c *OAuth2Client) fetch(call *tokenCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.fetchTimeout)
+	defer cancel()
+
+	c.mu.Lock()
+	refreshToken := c.refreshToken
+	c.mu.Unlock()
+
+	token, err := c.requestToken(ctx, refreshToken)
+
+	c.mu.Lock()
+	if err == nil {
+		c.token = token
+		if token.RefreshToken != "" {
+			c.refreshToken = token.RefreshToken // rotated by server
+		}
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+
+	call.token, call.err = token, err
+	close(call.done)
+}
+
+func (c *OAuth2Client) requestToken(ctx context.Context, refreshToken string) (*OAuth2Token, error) {
+
+	c.logger.Debug("request token: %s", c.tokenURL)
+
+	form := url.Values{}
+	for k, v := range c.params {
+		form[k] = v
+	}
+	form.Set("grant_type", c.grantType)
+	if c.grantType == "refresh_token" {
+		form.Set("refresh_token", refreshToken)
+	}
+	if len(c.scopes) > 0 {
+		form.Set("scope", strings.Join(c.scopes, " "))
+	}
+	if c.credentialsBody {
+		form.Set("client_id", c.clientID)
+		form.Set("client_secret", c.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", ContentTypeForm)
+	req.Header.Set("Accept", ContentTypeJSON)
+	if !c.credentialsBody {
+		req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	}
+
+	now := c.clock.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &TransportError{Err: err}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		he := newHTTPError(resp, 1)
+		var body struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.Unmarshal(he.Body, &body) != nil || body.Error == "" {
+			return nil, he
+		}
+		return nil, &OAuth2Error{Code: body.Error, Description: body.ErrorDescription, HTTP: he}
+	}
+
+	var body struct {
+		AccessToken  string      `json:"access_token"`
+		TokenType    string      `json:"token_type"`
+		RefreshToken string      `json:"refresh_token"`
+		ExpiresIn    json.Number `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("oauth2: could not decode token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("oauth2: token response has no access token")
+	}
+	token := &OAuth2Token{
+		AccessToken:  body.AccessToken,
+		TokenType:    body.TokenType,
+		RefreshToken: body.RefreshToken,
+	}
+	if expiresIn, err := body.ExpiresIn.Int64(); err == nil && expiresIn > 0 {
+		token.Expiry = now.Add(time.Duration(expiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+func authorize(req *http.Request, token *OAuth2Token) *http.Request {
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	req.Header.Set("Authorization", tokenType+" "+token.AccessToken)
+	return req
+}
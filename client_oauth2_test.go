@@ -0,0 +1,170 @@
+package rc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenServer issues tokens "t1", "t2", ... valid for expiresIn seconds
+func tokenServer(t *testing.T, expiresIn int, check func(r *http.Request)) (*httptest.Server, *atomic.Int32) {
+	issued := &atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, r.ParseForm())
+		if check != nil {
+			check(r)
+		}
+		n := issued.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fmt.Sprintf("t%d", n),
+			"token_type":    "bearer",
+			"expires_in":    expiresIn,
+			"refresh_token": fmt.Sprintf("r%d", n),
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, issued
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	tokens, issued := tokenServer(t, 3600, func(r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "id", id)
+		assert.Equal(t, "secret", secret)
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "read write", r.PostForm.Get("scope"))
+		assert.Equal(t, "api", r.PostForm.Get("audience"))
+	})
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer t1", r.Header.Get("Authorization"))
+	},
+		WithOAuth2Option(WithOAuth2ClientCredentials(tokens.URL, "id", "secret", "read", "write")),
+		WithOAuth2Option(WithOAuth2Param("audience", "api")),
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := Get[string](context.Background(), c)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), issued.Load())
+}
+func TestOAuth2TokenExpiry(t *testing.T) {
+	tokens, issued := tokenServer(t, 60, func(r *http.Request) {
+		assert.Equal(t, "id", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+	})
+	clock := newManualClock()
+	c := createTestServerClient(t, okHandler,
+		WithOAuth2Option(WithOAuth2ClientCredentials(tokens.URL, "id", "secret")),
+		WithOAuth2Option(WithOAuth2CredentialsInBody()),
+		WithOAuth2Option(WithOAuth2ExpirySkew(10*time.Second)),
+		WithOAuth2Option(WithOAuth2Clock(clock)),
+	)
+	token := func() string {
+		tok, err := c.(*OAuth2Client).Token(context.Background())
+		assert.NoError(t, err)
+		return tok.AccessToken
+	}
+	assert.Equal(t, "t1", token())
+	clock.Advance(49 * time.Second)
+	assert.Equal(t, "t1", token())
+	clock.Advance(time.Second)
+	assert.Equal(t, "t2", token())
+	assert.Equal(t, int32(2), issued.Load())
+}
+func TestOAuth2RetryUnauthorized(t *testing.T) {
+	tokens, issued := tokenServer(t, 3600, nil)
+	calls := &atomic.Int32{}
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		b, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer t2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(b)
+	},
+		WithOAuth2Option(WithOAuth2ClientCredentials(tokens.URL, "id", "secret")),
+	)
+	item, _, err := Post[testItem](context.Background(), c, testItem{ID: 1, Name: "replayed"})
+	assert.NoError(t, err)
+	assert.Equal(t, testItem{ID: 1, Name: "replayed"}, item)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, int32(2), issued.Load())
+
+	// fresh token is rejected as well: no more attempts
+	c = createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	},
+		WithOAuth2Option(WithOAuth2ClientCredentials(tokens.URL, "id", "secret")),
+	)
+	_, res, err := Get[testItem](context.Background(), c)
+	assert.True(t, IsUnauthorized(err))
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, int32(4), issued.Load())
+}
+func TestOAuth2RefreshToken(t *testing.T) {
+	expected := "r0"
+	tokens, _ := tokenServer(t, 1, func(r *http.Request) {
+		assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		assert.Equal(t, expected, r.PostForm.Get("refresh_token"))
+		expected = "r1" // rotated
+	})
+	c, err := NewOAuth2Client(createTestServerClient(t, okHandler),
+		WithOAuth2RefreshToken(tokens.URL, "id", "secret", "r0"),
+		WithOAuth2ExpirySkew(0),
+		WithOAuth2Clock(newManualClock()),
+	)
+	assert.NoError(t, err)
+	for i := 1; i <= 2; i++ {
+		tok, err := c.(*OAuth2Client).Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("t%d", i), tok.AccessToken)
+		c.(*OAuth2Client).clock.(*manualClock).Advance(time.Second)
+	}
+
+	_, err = NewOAuth2Client(c, WithOAuth2RefreshToken(tokens.URL, "id", "secret", ""))
+	assert.Error(t, err)
+	_, err = NewOAuth2Client(c)
+	assert.Error(t, err)
+}
+func TestOAuth2Error(t *testing.T) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"unknown client"}`))
+	}))
+	defer tokens.Close()
+	calls := 0
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	},
+		WithOAuth2Option(WithOAuth2ClientCredentials(tokens.URL, "id", "secret")),
+	)
+	_, _, err := Get[string](context.Background(), c)
+	var oe *OAuth2Error
+	if assert.ErrorAs(t, err, &oe) {
+		assert.Equal(t, "invalid_client", oe.Code)
+		assert.Equal(t, "unknown client", oe.Description)
+		assert.Equal(t, http.StatusBadRequest, oe.HTTP.StatusCode)
+	}
+	assert.Equal(t, 0, calls)
+}
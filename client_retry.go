@@ -2,26 +2,51 @@ package rc
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"go.slink.ws/logging"
-	"io"
 	"net/http"
-	"net/url"
 	"time"
 )
 
+// RetryError is returned by RetryClient when it gives up retrying. It wraps both last
+// upstream error and context error (if retrying was interrupted by context).
+type RetryError struct {
+	Attempts int
+	Err      error
+	CtxErr   error
+}
+
+func (e *RetryError) Error() string {
+	if e.CtxErr != nil {
+		return fmt.Sprintf("retry interrupted after %d attempt(s): %s; last error: %s", e.Attempts, e.CtxErr, e.Err)
+	}
+	return fmt.Sprintf("retry failed after %d attempt(s): %s", e.Attempts, e.Err)
+}
+func (e *RetryError) Unwrap() []error {
+	if e.CtxErr != nil {
+		return []error{e.Err, e.CtxErr}
+	}
+	return []error{e.Err}
+}
+
 type RetryClient struct {
-	client      Client
+	layer
 	maxAttempts int
-	delay       time.Duration
+	backoff     BackoffPolicy
+	maxDelay    time.Duration
+	maxElapsed  time.Duration
+	policy      RetryPolicy
+	clock       Clock
 	logger      logging.Logger
 }
 
 func NewRetryClient(client Client, options ...RetryClientOption) (Client, error) {
 	c := &RetryClient{
-		client:      client,
+		layer:       layer{client},
 		maxAttempts: -1,
-		delay:       3 * time.Second,
+		backoff:     ConstantBackoff(3 * time.Second),
+		policy:      DefaultRetryPolicy(),
+		clock:       SystemClock(),
 		logger:      logging.GetNoOpLogger(),
 	}
 	for _, option := range options {
@@ -31,48 +56,15 @@ func NewRetryClient(client Client, options ...RetryClientOption) (Client, error)
 	return c, nil
 }
 
-func (c *RetryClient) GetBaseURL() *url.URL {
-	c.logger.Trace("get base url")
-	return c.client.GetBaseURL()
-}
-func (c *RetryClient) NewRequest(options ...RequestOption) (*http.Request, error) {
-	c.logger.Trace("new request")
-	return c.client.NewRequest(options...)
+// RetryMiddleware returns retry layer as Middleware (see Chain).
+// Options are validated right away.
+func RetryMiddleware(options ...RetryClientOption) (Middleware, error) {
+	return layerMiddleware(NewRetryClient, options)
 }
 
 func (c *RetryClient) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, int, error) {
 	c.logger.Trace("do: %s %s", req.Method, req.URL)
-	resp, status, err := c.BareDo(ctx, req)
-	if err != nil {
-		return resp, status, err
-	}
-	switch v := v.(type) {
-	case nil:
-		return resp, status, nil
-	case io.Writer:
-		_, err = io.Copy(v, resp.Body)
-	default:
-		var b []byte
-		b, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, status, err
-		}
-		decErr := json.Unmarshal(b, &v)
-		if decErr == io.EOF {
-			decErr = nil // ignore EOF errors caused by empty response body
-		}
-		if decErr != nil {
-			err = decErr
-		}
-	}
-	if err != nil {
-		return nil, status, err
-	}
-	err = resp.Body.Close()
-	if err != nil {
-		return nil, status, err
-	}
-	return resp, status, err
+	return do(ctx, c.BareDo, req, v)
 }
 func (c *RetryClient) BareDo(ctx context.Context, req *http.Request) (*Response, int, error) {
 
@@ -85,26 +77,61 @@ func (c *RetryClient) BareDo(ctx context.Context, req *http.Request) (*Response,
 	var err error
 	var res *Response
 	var status int
-	attempt := 0
-	for attempt < c.maxAttempts || c.maxAttempts < 0 && ctx.Err() == nil {
-		res, status, err = c.client.BareDo(ctx, req)
-		if err != nil {
-			switch e := err.(type) {
-			case ErrTooManyRequests:
-				c.logger.Debug("too many requests, wait for %v %s", e.Delay.Seconds(), "second(s)")
-				time.Sleep(e.Delay)
-			case ErrResourceNotFound:
-				c.logger.Debug("resource not found: %s", e.Resource)
-				return nil, status, err
-			default:
-				c.logger.Debug("error: %s, wait for %v %s", err, c.delay.Seconds(), "second(s)")
-				time.Sleep(c.delay)
-				attempt++
+	var delay time.Duration
+	sent := 0
+	start := c.clock.Now()
+	for {
+		r := req
+		if sent > 0 {
+			// request body was consumed by previous attempt
+			var rwErr error
+			if r, rwErr = rewindRequest(req); rwErr != nil {
+				c.logger.Debug("could not retry: %s", rwErr)
+				return nil, status, &RetryError{Attempts: sent, Err: fmt.Errorf("%w: %w", rwErr, err)}
 			}
-			continue
 		}
-		return res, status, nil
+		sent++
+		res, status, err = c.client.BareDo(withAttempt(ctx, sent), r)
+		if err == nil {
+			return res, status, nil
+		}
+		if ctx.Err() != nil {
+			return nil, status, &RetryError{Attempts: sent, Err: err, CtxErr: ctx.Err()}
+		}
+		if !c.policy.ShouldRetry(r, status, err, sent) {
+			c.logger.Debug("error: %s, not retryable", err)
+			return nil, status, err
+		}
+		if c.maxAttempts >= 0 && sent >= c.maxAttempts {
+			return nil, status, &RetryError{Attempts: sent, Err: err}
+		}
+		if d, ok := serverDelay(err); ok {
+			delay = d
+		} else {
+			delay = c.nextDelay(sent, delay)
+		}
+		if c.maxElapsed > 0 && c.clock.Now().Sub(start)+delay > c.maxElapsed {
+			c.logger.Debug("error: %s, retry budget %s exhausted", err, c.maxElapsed)
+			return nil, status, &RetryError{Attempts: sent, Err: err}
+		}
+		c.logger.Debug("error: %s, wait for %v %s", err, delay.Seconds(), "second(s)")
+		if ctxErr := wait(ctx, c.clock, delay); ctxErr != nil {
+			return nil, status, &RetryError{Attempts: sent, Err: err, CtxErr: ctxErr}
+		}
 	}
-	return nil, status, err
 
 }
+
+// Close closes wrapped client if it implements io.Closer.
+func (c *RetryClient) Close() error {
+	c.logger.Trace("close")
+	return closeClient(c.client)
+}
+
+func (c *RetryClient) nextDelay(attempt int, previous time.Duration) time.Duration {
+	delay := c.backoff.Delay(attempt, previous)
+	if c.maxDelay > 0 && delay > c.maxDelay {
+		delay = c.maxDelay
+	}
+	return delay
+}
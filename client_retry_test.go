@@ -0,0 +1,202 @@
+package rc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingHandler fails first n requests with given status and then calls next
+func failingHandler(n int32, status int, next http.HandlerFunc) (http.HandlerFunc, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.WriteHeader(status)
+			return
+		}
+		next(w, r)
+	}, calls
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	handler, calls := failingHandler(2, http.StatusInternalServerError, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(b)
+	})
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(3)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+	)
+	item, _, err := Post[testItem](context.Background(), c, testItem{ID: 7, Name: "replayed"},
+		WithHeader("Idempotency-Key", "k1"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, testItem{ID: 7, Name: "replayed"}, item)
+	assert.Equal(t, int32(3), calls.Load())
+}
+func TestRetryReplaysSeekableMultipart(t *testing.T) {
+	handler, calls := failingHandler(1, http.StatusBadGateway, func(w http.ResponseWriter, r *http.Request) {
+		f, _, err := r.FormFile("file")
+		if assert.NoError(t, err) {
+			_, _ = io.Copy(w, f)
+		}
+	})
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(2)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+	)
+	out, _, err := Post[string](context.Background(), c, nil,
+		WithMultipartFile("file", "f.txt", strings.NewReader("seekable")),
+		WithHeader("Idempotency-Key", "k2"),
+		WithBodyCodec(TextCodec()),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "seekable", out)
+	assert.Equal(t, int32(2), calls.Load())
+}
+func TestRetryRefusesStreamingBody(t *testing.T) {
+	handler, calls := failingHandler(5, http.StatusInternalServerError, nil)
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(3)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+	)
+	_, _, err := Post[string](context.Background(), c, nil,
+		WithMultipartFile("file", "f.txt", io.MultiReader(strings.NewReader("stream"))),
+		WithHeader("Idempotency-Key", "k3"),
+	)
+	assert.ErrorIs(t, err, ErrBodyNotReplayable)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+// stoppedClock never fires timers
+type stoppedClock struct {
+	waits atomic.Int32
+}
+
+func (c *stoppedClock) Now() time.Time {
+	return time.Now()
+}
+func (c *stoppedClock) After(time.Duration) <-chan time.Time {
+	c.waits.Add(1)
+	return make(chan time.Time)
+}
+
+func TestRetryWaitInterruptedByContext(t *testing.T) {
+	handler, calls := failingHandler(100, http.StatusInternalServerError, nil)
+	clock := &stoppedClock{}
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(-1)),
+		WithRetryOption(WithRetryDelay(time.Hour)),
+		WithRetryOption(WithRetryClock(clock)),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for clock.waits.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	_, _, err := Get[testItem](ctx, c)
+	var re *RetryError
+	if assert.ErrorAs(t, err, &re) {
+		assert.Equal(t, 1, re.Attempts)
+		assert.Error(t, re.Err)
+	}
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), calls.Load())
+}
+func TestRetryAttemptCount(t *testing.T) {
+	handler, _ := failingHandler(2, http.StatusInternalServerError, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(5)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+	)
+	_, res, err := Get[testItem](context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Response.Attempt)
+
+	handler, _ = failingHandler(2, http.StatusInternalServerError, nil)
+	_, _, err = Get[testItem](context.Background(), createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(2)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+	))
+	var re *RetryError
+	if assert.ErrorAs(t, err, &re) {
+		assert.Equal(t, 2, re.Attempts)
+	}
+}
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		status  int
+		headers map[string]string
+		calls   int32
+	}{
+		{"get server error", http.MethodGet, http.StatusInternalServerError, nil, 3},
+		{"get bad request", http.MethodGet, http.StatusBadRequest, nil, 1},
+		{"get unauthorized", http.MethodGet, http.StatusUnauthorized, nil, 1},
+		{"get not found", http.MethodGet, http.StatusNotFound, nil, 1},
+		{"post server error", http.MethodPost, http.StatusInternalServerError, nil, 1},
+		{"post with key", http.MethodPost, http.StatusServiceUnavailable, map[string]string{"Idempotency-Key": "k"}, 3},
+		{"post too many requests", http.MethodPost, http.StatusTooManyRequests, nil, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, calls := failingHandler(2, tt.status, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			c := createTestServerClient(t, handler,
+				WithRetryOption(WithMaxAttempts(3)),
+				WithRetryOption(WithRetryDelay(time.Millisecond)),
+			)
+			options := []RequestOption{WithMethod(tt.method)}
+			for k, v := range tt.headers {
+				options = append(options, WithHeader(k, v))
+			}
+			req, err := c.NewRequest(options...)
+			assert.NoError(t, err)
+			_, _, _ = c.Do(context.Background(), req, nil)
+			assert.Equal(t, tt.calls, calls.Load())
+		})
+	}
+}
+func TestRetryCustomPolicy(t *testing.T) {
+	handler, calls := failingHandler(100, http.StatusConflict, nil)
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(-1)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+		WithRetryOption(WithRetryPolicy(RetryPolicyFunc(func(_ *http.Request, status int, _ error, attempt int) bool {
+			return status == http.StatusConflict && attempt < 4
+		}))),
+	)
+	_, res, err := Post[testItem](context.Background(), c, testItem{})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, int32(4), calls.Load())
+}
+func TestRetryCircuitOpen(t *testing.T) {
+	handler, calls := failingHandler(100, http.StatusInternalServerError, nil)
+	clock := newManualClock()
+	c := createTestServerClient(t, handler,
+		WithCircuitBreakerOption(WithFailureThreshold(1)),
+		WithCircuitBreakerOption(WithOpenDuration(time.Minute)),
+		WithCircuitBreakerOption(WithBreakerClock(clock)),
+		WithRetryOption(WithMaxAttempts(-1)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+		WithRetryOption(WithRetryClock(clock)),
+	)
+	_, res, err := Get[testItem](context.Background(), c)
+	assert.ErrorAs(t, err, &ErrCircuitOpen{})
+	assert.Equal(t, 0, res.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
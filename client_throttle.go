@@ -2,51 +2,88 @@ package rc
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"go.slink.ws/logging"
-	"io"
 	"net/http"
-	"net/url"
-	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type ErrResourceNotFound struct {
 	Resource string
+	HTTP     *HTTPError
 }
 
 func (e ErrResourceNotFound) Error() string {
 	return fmt.Sprintf("Resource Not Found: %s", e.Resource)
 }
+func (e ErrResourceNotFound) Unwrap() error {
+	return unwrapHTTPError(e.HTTP)
+}
 
+// ErrTooManyRequests is returned for 429 responses and by ThrottleClient, when it's out
+// of tokens (HTTP is nil in the latter case).
 type ErrTooManyRequests struct {
 	Delay time.Duration
+	HTTP  *HTTPError
 }
 
 func (e ErrTooManyRequests) Error() string {
 	return fmt.Sprintf("Too Many Requests; Wait %s", e.Delay)
 }
+func (e ErrTooManyRequests) Unwrap() error {
+	return unwrapHTTPError(e.HTTP)
+}
+
+type ErrServiceUnavailable struct {
+	Delay time.Duration
+	HTTP  *HTTPError
+}
+
+func (e ErrServiceUnavailable) Error() string {
+	return fmt.Sprintf("Service Unavailable; Wait %s", e.Delay)
+}
+func (e ErrServiceUnavailable) Unwrap() error {
+	return unwrapHTTPError(e.HTTP)
+}
+
+type ThrottleMode int
 
-type callerFunc func(ctx context.Context, req *http.Request) (*Response, int, error)
+const (
+	// ThrottleReject makes ThrottleClient fail with ErrTooManyRequests when tokens run out
+	ThrottleReject ThrottleMode = iota
+	// ThrottleWait makes ThrottleClient block until token is available
+	ThrottleWait
+)
 
 type ThrottleClient struct {
-	client         Client
-	maxTokens      int
-	refillTokens   int
-	refillInterval time.Duration
-	logger         logging.Logger
-	caller         callerFunc
+	layer
+	maxTokens        int
+	refillTokens     int
+	refillInterval   time.Duration
+	mode             ThrottleMode
+	maxWait          time.Duration
+	clock            Clock
+	logger           logging.Logger
+	keyFunc          ThrottleKeyFunc
+	limits           map[string]ThrottleLimit
+	idleTTL          time.Duration
+	adaptive         bool
+	rateLimitHeaders []RateLimitHeaders
+	limiter          *keyedLimiter
+	caller           Handler
+	closed           atomic.Bool
 }
 
 func NewThrottleClient(client Client, options ...ThrottleClientOption) (Client, error) {
 
 	c := &ThrottleClient{
-		client:         client,
+		layer:          layer{client},
 		maxTokens:      30,
 		refillTokens:   30,
 		refillInterval: time.Minute,
+		clock:          SystemClock(),
 		logger:         logging.GetNoOpLogger(),
 	}
 
@@ -54,70 +91,58 @@ func NewThrottleClient(client Client, options ...ThrottleClientOption) (Client,
 		option(c)
 	}
 
+	defaults := ThrottleLimit{
+		MaxTokens:      c.maxTokens,
+		RefillTokens:   c.refillTokens,
+		RefillInterval: c.refillInterval,
+	}
+	if err := defaults.validate(); err != nil {
+		return nil, err
+	}
+	limits := make(map[string]ThrottleLimit, len(c.limits))
+	for key, limit := range c.limits {
+		limit = limit.inherit(defaults)
+		if err := limit.validate(); err != nil {
+			return nil, fmt.Errorf("throttle key %q: %w", key, err)
+		}
+		limits[key] = limit
+	}
+	if c.keyFunc != nil && c.idleTTL == 0 {
+		// bucket idle for twice its full refill time holds no state worth keeping
+		c.idleTTL = 2 * defaults.refillTime()
+		for _, limit := range limits {
+			c.idleTTL = max(c.idleTTL, 2*limit.refillTime())
+		}
+	}
+
+	c.limiter = newKeyedLimiter(c.keyFunc, defaults, limits, c.idleTTL, c.clock)
 	c.caller = c.throttler(c.client.BareDo)
 	c.logger.Trace("new client")
 
 	return c, nil
 }
 
-func (c *ThrottleClient) GetBaseURL() *url.URL {
-	c.logger.Trace("get base url")
-	return c.client.GetBaseURL()
-}
-func (c *ThrottleClient) NewRequest(options ...RequestOption) (*http.Request, error) {
-	c.logger.Trace("new request")
-	return c.client.NewRequest(options...)
+// ThrottleMiddleware returns throttle layer as Middleware (see Chain).
+// Options are validated right away.
+func ThrottleMiddleware(options ...ThrottleClientOption) (Middleware, error) {
+	return layerMiddleware(NewThrottleClient, options)
 }
 
 func (c *ThrottleClient) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, int, error) {
 	c.logger.Trace("do: %s %s", req.Method, req.URL)
-	resp, status, err := c.BareDo(ctx, req)
-	if err != nil {
-		return resp, status, err
-	}
-	switch v := v.(type) {
-	case nil:
-	case io.Writer:
-		_, err = io.Copy(v, resp.Body)
-	default:
-		var b []byte
-		b, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, status, err
-		}
-		decErr := json.Unmarshal(b, &v)
-		if decErr == io.EOF {
-			decErr = nil // ignore EOF errors caused by empty response body
-		}
-		if decErr != nil {
-			err = decErr
-		}
-	}
-	if err != nil {
-		return nil, status, err
-	}
-	err = resp.Body.Close()
-	if err != nil {
-		return nil, status, err
-	}
-	return resp, status, err
+	return do(ctx, c.BareDo, req, v)
 }
 
 func (c *ThrottleClient) BareDo(ctx context.Context, req *http.Request) (*Response, int, error) {
 	c.logger.Trace("bare do: %s %s", req.Method, req.URL)
-	//return c.throttler(c.client.BareDo)(ctx, req)
 	return c.caller(ctx, req)
 }
 
-func (c *ThrottleClient) throttler(e callerFunc) callerFunc {
+func (c *ThrottleClient) throttler(e Handler) Handler {
 
 	c.logger.Trace("throttler enter")
 	defer c.logger.Trace("throttler exit")
 
-	var tokens = c.maxTokens
-	var once sync.Once
-	var lastRefill time.Time
-
 	return func(ctx context.Context, req *http.Request) (*Response, int, error) {
 		c.logger.Trace("throttler closure: enter")
 		defer c.logger.Trace("throttler closure: exit")
@@ -125,61 +150,78 @@ func (c *ThrottleClient) throttler(e callerFunc) callerFunc {
 		if ctx == nil {
 			return nil, http.StatusInternalServerError, ErrNonNilContext
 		}
-
+		if c.closed.Load() {
+			return nil, 0, ErrClientClosed
+		}
 		if ctx.Err() != nil {
-			return nil, http.StatusRequestTimeout, ctx.Err()
+			return nil, 0, ctx.Err()
 		}
-		once.Do(func() {
-			lastRefill = time.Now()
-			ticker := time.NewTicker(c.refillInterval)
-			go func() {
-				defer ticker.Stop()
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					case <-ticker.C:
-						lastRefill = time.Now()
-						if tokens < c.maxTokens {
-							c.logger.Debug("throttler: refill tokens")
-							t := tokens + c.refillTokens
-							if t > c.maxTokens {
-								t = c.maxTokens
-							}
-							tokens = t
-						}
-					}
-				}
-			}()
-		})
-		if tokens <= 0 {
-			delay := c.calculateDelay(lastRefill)
-			c.logger.Debug("throttler: wait for %v %s", delay.Seconds(), "second(s)")
-			return nil, http.StatusTooManyRequests, ErrTooManyRequests{
-				Delay: delay,
+
+		bucket := c.limiter.bucket(req)
+		if err := c.acquire(ctx, bucket); err != nil {
+			if ctx.Err() != nil {
+				return nil, 0, err
 			}
+			return nil, http.StatusTooManyRequests, err
 		}
 		c.logger.Trace("throttler: call external service")
-		tokens--
 
 		res, status, err := e(ctx, req)
 
 		// if our throttling was not enough, and we received 429 error from external service
-		if errors.As(err, &ErrTooManyRequests{}) {
+		var tmr ErrTooManyRequests
+		if errors.As(err, &tmr) {
 			c.logger.Warning("throttler: too many requests error from external service")
-			tokens /= 5 // чтобы не в ноль сбрасывать; чтобы по возможности ждать не весь refillInterval
+			delay := bucket.penalize()
+			if tmr.Delay > 0 {
+				delay = tmr.Delay
+			}
 			return nil, http.StatusTooManyRequests, ErrTooManyRequests{
-				Delay: c.calculateDelay(lastRefill) / 2,
+				Delay: delay,
+				HTTP:  tmr.HTTP,
+			}
+		}
+
+		if err == nil && c.adaptive && res != nil {
+			if state, ok := parseRateLimit(res.Header, c.rateLimitHeaders, c.clock.Now()); ok {
+				c.logger.Trace("throttler: sync with upstream rate limit, remaining %d", state.remaining)
+				bucket.sync(state)
 			}
 		}
 
 		return res, status, err
 	}
 }
-func (c *ThrottleClient) calculateDelay(lastRefill time.Time) time.Duration {
-	ms := c.refillInterval.Milliseconds() - time.Now().Sub(lastRefill).Milliseconds() + 100
-	if ms < 0 {
-		ms = 0
+
+// acquire takes token from bucket. In ThrottleWait mode it blocks until token
+// is available, context is done or expected wait exceeds max wait.
+func (c *ThrottleClient) acquire(ctx context.Context, bucket *tokenBucket) error {
+	var waited time.Duration
+	for {
+		ok, delay := bucket.take()
+		if ok {
+			return nil
+		}
+		if c.mode != ThrottleWait || c.maxWait > 0 && waited+delay > c.maxWait {
+			c.logger.Debug("throttler: wait for %v %s", delay.Seconds(), "second(s)")
+			return ErrTooManyRequests{
+				Delay: delay,
+			}
+		}
+		c.logger.Debug("throttler: waiting for %v %s", delay.Seconds(), "second(s)")
+		if err := wait(ctx, c.clock, delay); err != nil {
+			return err
+		}
+		waited += delay
+	}
+}
+
+// Close releases throttle client; any subsequent request fails with ErrClientClosed.
+// If wrapped client implements io.Closer, it is closed as well.
+func (c *ThrottleClient) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
 	}
-	return time.Duration(ms) * time.Millisecond
+	c.logger.Trace("close")
+	return closeClient(c.client)
 }
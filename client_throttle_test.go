@@ -0,0 +1,292 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// manualClock is advanced explicitly by test
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.Advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func okHandler(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestThrottleRefill(t *testing.T) {
+	clock := newManualClock()
+	c := createTestServerClient(t, okHandler,
+		WithThrottleOption(WithMaxTokens(3)),
+		WithThrottleOption(WithRefillTokens(2)),
+		WithThrottleOption(WithRefillInterval(time.Minute)),
+		WithThrottleOption(WithThrottleClock(clock)),
+	)
+	for i := 0; i < 3; i++ {
+		_, _, err := Get[testItem](context.Background(), c)
+		assert.NoError(t, err)
+	}
+	clock.Advance(20 * time.Second)
+	_, res, err := Get[testItem](context.Background(), c)
+	var tmr ErrTooManyRequests
+	if assert.ErrorAs(t, err, &tmr) {
+		assert.Equal(t, 40*time.Second+100*time.Millisecond, tmr.Delay)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	clock.Advance(40 * time.Second)
+	for i := 0; i < 2; i++ {
+		_, _, err = Get[testItem](context.Background(), c)
+		assert.NoError(t, err)
+	}
+	_, _, err = Get[testItem](context.Background(), c)
+	assert.ErrorAs(t, err, &ErrTooManyRequests{})
+}
+func TestThrottleConcurrent(t *testing.T) {
+	var served atomic.Int32
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		served.Add(1)
+	},
+		WithThrottleOption(WithMaxTokens(50)),
+		WithThrottleOption(WithRefillInterval(time.Hour)),
+	)
+	var wg sync.WaitGroup
+	var throttled atomic.Int32
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// cancelled contexts of some callers must not affect others
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			_, _, err := Get[testItem](ctx, c)
+			if err != nil {
+				assert.ErrorAs(t, err, &ErrTooManyRequests{})
+				throttled.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(50), served.Load())
+	assert.Equal(t, int32(150), throttled.Load())
+}
+func TestThrottleClose(t *testing.T) {
+	c := createTestServerClient(t, okHandler,
+		WithThrottleOption(WithMaxTokens(5)),
+		WithRetryOption(WithMaxAttempts(1)),
+	)
+	_, _, err := Get[testItem](context.Background(), c)
+	assert.NoError(t, err)
+	assert.NoError(t, closeClient(c))
+	_, res, err := Get[testItem](context.Background(), c)
+	assert.ErrorIs(t, err, ErrClientClosed)
+	assert.Equal(t, 0, res.StatusCode)
+}
+func TestThrottleWaitMode(t *testing.T) {
+	clock := newManualClock()
+	c := createTestServerClient(t, okHandler,
+		WithThrottleOption(WithMaxTokens(1)),
+		WithThrottleOption(WithRefillTokens(1)),
+		WithThrottleOption(WithRefillInterval(time.Minute)),
+		WithThrottleOption(WithThrottleMode(ThrottleWait)),
+		WithThrottleOption(WithThrottleClock(clock)),
+	)
+	start := clock.Now()
+	for i := 0; i < 3; i++ {
+		_, _, err := Get[testItem](context.Background(), c)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2*time.Minute+100*time.Millisecond, clock.Now().Sub(start))
+}
+func TestThrottleWaitModeMaxWait(t *testing.T) {
+	clock := newManualClock()
+	c := createTestServerClient(t, okHandler,
+		WithThrottleOption(WithMaxTokens(1)),
+		WithThrottleOption(WithRefillInterval(time.Minute)),
+		WithThrottleOption(WithThrottleMode(ThrottleWait)),
+		WithThrottleOption(WithThrottleMaxWait(30*time.Second)),
+		WithThrottleOption(WithThrottleClock(clock)),
+	)
+	_, _, err := Get[testItem](context.Background(), c)
+	assert.NoError(t, err)
+	_, _, err = Get[testItem](context.Background(), c)
+	assert.ErrorAs(t, err, &ErrTooManyRequests{})
+	assert.Equal(t, time.Duration(0), clock.Now().Sub(newManualClock().Now()))
+}
+func TestThrottleWaitModeContext(t *testing.T) {
+	c := createTestServerClient(t, okHandler,
+		WithThrottleOption(WithMaxTokens(1)),
+		WithThrottleOption(WithRefillInterval(time.Hour)),
+		WithThrottleOption(WithThrottleMode(ThrottleWait)),
+	)
+	_, _, err := Get[testItem](context.Background(), c)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, res, err := Get[testItem](ctx, c)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, res.StatusCode)
+}
+func TestThrottleKeyByRoute(t *testing.T) {
+	clock := newManualClock()
+	c := createTestServerClient(t, okHandler,
+		WithThrottleOption(WithMaxTokens(1)),
+		WithThrottleOption(WithThrottleKey(ThrottleKeyByRoute("GET /search", "POST /items/*"))),
+		WithThrottleOption(WithThrottleKeyLimit("GET /search", ThrottleLimit{MaxTokens: 3, RefillInterval: time.Minute})),
+		WithThrottleOption(WithThrottleClock(clock)),
+	)
+	count := func(options ...RequestOption) int {
+		n := 0
+		for i := 0; i < 5; i++ {
+			req, err := c.NewRequest(options...)
+			assert.NoError(t, err)
+			if _, _, err = c.Do(context.Background(), req, nil); err == nil {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 3, count(WithQueryPath("/search")))
+	assert.Equal(t, 1, count(WithMethod(http.MethodPost), WithQueryPath("/items/1")))
+	assert.Equal(t, 0, count(WithMethod(http.MethodPost), WithQueryPath("/items/2")))
+	assert.Equal(t, 1, count(WithQueryPath("/other")))
+}
+func TestThrottleKeyIdleEviction(t *testing.T) {
+	clock := newManualClock()
+	limiter := newKeyedLimiter(ThrottleKeyByHeader("X-Tenant"), ThrottleLimit{MaxTokens: 1, RefillInterval: time.Hour}, nil, time.Minute, clock)
+	for _, tenant := range []string{"a", "b", "c"} {
+		req, _ := http.NewRequest(http.MethodGet, "https://test.com", nil)
+		req.Header.Set("X-Tenant", tenant)
+		ok, _ := limiter.bucket(req).take()
+		assert.True(t, ok)
+	}
+	assert.Equal(t, 3, limiter.size())
+	clock.Advance(30 * time.Second)
+	req, _ := http.NewRequest(http.MethodGet, "https://test.com", nil)
+	req.Header.Set("X-Tenant", "a")
+	ok, _ := limiter.bucket(req).take()
+	assert.False(t, ok)
+	clock.Advance(45 * time.Second)
+	req.Header.Set("X-Tenant", "d")
+	limiter.bucket(req)
+	assert.Equal(t, 2, limiter.size()) // "b" & "c" evicted
+}
+func TestThrottleUpstreamTooManyRequests(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeProblemJSON)
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"title":"Slow down","status":429}`))
+	},
+		WithThrottleOption(WithMaxTokens(10)),
+	)
+	_, res, err := Get[testItem](context.Background(), c)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	var tmr ErrTooManyRequests
+	if assert.ErrorAs(t, err, &tmr) {
+		assert.Equal(t, 7*time.Second, tmr.Delay)
+	}
+	var he *HTTPError
+	if assert.ErrorAs(t, err, &he) {
+		assert.Equal(t, "7", he.Header.Get("Retry-After"))
+		if assert.NotNil(t, he.Problem) {
+			assert.Equal(t, "Slow down", he.Problem.Title)
+		}
+	}
+}
+func TestThrottleKeyLimitDefaults(t *testing.T) {
+	clock := newManualClock()
+	base, err := NewBasicClient(WithBaseUrl("https://test.com"))
+	assert.NoError(t, err)
+	c, err := NewThrottleClient(base,
+		WithRefillTokens(1),
+		WithRefillInterval(time.Minute),
+		WithThrottleKey(ThrottleKeyByHeader("X-Tenant")),
+		WithThrottleKeyLimit("a", ThrottleLimit{MaxTokens: 2}),
+		WithThrottleClock(clock),
+	)
+	assert.NoError(t, err)
+	limiter := c.(*ThrottleClient).limiter
+	req, _ := http.NewRequest(http.MethodGet, "https://test.com", nil)
+	req.Header.Set("X-Tenant", "a")
+	b := limiter.bucket(req)
+	for i := 0; i < 2; i++ {
+		ok, _ := b.take()
+		assert.True(t, ok)
+	}
+	ok, _ := b.take()
+	assert.False(t, ok)
+	clock.Advance(time.Minute)
+	ok, _ = b.take() // refilled with client default
+	assert.True(t, ok)
+}
+func TestThrottleInvalidLimits(t *testing.T) {
+	base, err := NewBasicClient(WithBaseUrl("https://test.com"))
+	assert.NoError(t, err)
+	for _, option := range []ThrottleClientOption{
+		WithMaxTokens(0),
+		WithRefillTokens(-1),
+		WithRefillInterval(0),
+		WithThrottleKeyLimit("a", ThrottleLimit{MaxTokens: -1}),
+	} {
+		_, err = NewThrottleClient(base, option)
+		assert.Error(t, err)
+	}
+}
+func TestThrottleKeyDefaultIdleTTL(t *testing.T) {
+	base, err := NewBasicClient(WithBaseUrl("https://test.com"))
+	assert.NoError(t, err)
+	tests := []struct {
+		name    string
+		options []ThrottleClientOption
+		idleTTL time.Duration
+	}{
+		{"no key", nil, 0},
+		{"key", []ThrottleClientOption{
+			WithThrottleKey(ThrottleKeyByHost()),
+			WithMaxTokens(10), WithRefillTokens(4), WithRefillInterval(time.Minute),
+		}, 6 * time.Minute},
+		{"key limit", []ThrottleClientOption{
+			WithThrottleKey(ThrottleKeyByHost()),
+			WithThrottleKeyLimit("slow", ThrottleLimit{RefillTokens: 1}),
+		}, 60 * time.Minute},
+		{"disabled", []ThrottleClientOption{
+			WithThrottleKey(ThrottleKeyByHost()),
+			WithThrottleIdleTTL(-1),
+		}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewThrottleClient(base, tt.options...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.idleTTL, c.(*ThrottleClient).limiter.idleTTL)
+		})
+	}
+}
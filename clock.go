@@ -0,0 +1,57 @@
+package rc
+
+import (
+	"context"
+	"time"
+)
+
+// Clock abstracts time source & timers used by client layers, so that waiting
+// can be controlled in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func SystemClock() Clock {
+	return systemClock{}
+}
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// wait blocks for given duration or until context is done; returns context error in latter case.
+func wait(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clock.After(d):
+		return nil
+	}
+}
+
+// region - attempt
+
+type attemptKey struct{}
+
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns number of current request attempt (starting with 1)
+// set by RetryClient. Returns 1 if request is not executed through retry layer.
+func AttemptFromContext(ctx context.Context) int {
+	if a, ok := ctx.Value(attemptKey{}).(int); ok {
+		return a
+	}
+	return 1
+}
+
+// endregion
@@ -0,0 +1,239 @@
+package rc
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeXML  = "application/xml"
+	ContentTypeForm = "application/x-www-form-urlencoded"
+	ContentTypeText = "text/plain; charset=utf-8"
+)
+
+// region - codec interface
+
+// Codec serializes request bodies & deserializes response bodies for a given content type.
+// Codec should return error wrapping ErrUnsupportedType for values it can't handle.
+type Codec interface {
+	ContentType() string
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+// endregion
+// region - codec registry
+
+// codecRegistry selects codec by (response) content type.
+type codecRegistry struct {
+	codecs map[string]Codec
+}
+
+func newCodecRegistry(codecs ...Codec) *codecRegistry {
+	r := &codecRegistry{
+		codecs: make(map[string]Codec),
+	}
+	for _, c := range codecs {
+		r.register(c)
+	}
+	return r
+}
+
+func (r *codecRegistry) register(codec Codec, contentTypes ...string) {
+	if len(contentTypes) == 0 {
+		contentTypes = []string{codec.ContentType()}
+	}
+	for _, ct := range contentTypes {
+		r.codecs[mediaType(ct)] = codec
+	}
+}
+
+// lookup returns codec registered for given content type, or nil if there is none.
+// Structured syntax suffixes (e.g. "application/problem+json") fall back to base type codec.
+func (r *codecRegistry) lookup(contentType string) Codec {
+	if r == nil || contentType == "" {
+		return nil
+	}
+	mt := mediaType(contentType)
+	if c, ok := r.codecs[mt]; ok {
+		return c
+	}
+	if i := strings.LastIndex(mt, "+"); i >= 0 {
+		if c, ok := r.codecs["application/"+mt[i+1:]]; ok {
+			return c
+		}
+	}
+	return nil
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mt
+}
+
+func defaultCodecRegistry() *codecRegistry {
+	r := newCodecRegistry(JSONCodec(), FormCodec(), TextCodec())
+	r.register(XMLCodec(), ContentTypeXML, "text/xml")
+	return r
+}
+
+// endregion
+// region - json
+
+type jsonCodec struct{}
+
+func JSONCodec() Codec {
+	return jsonCodec{}
+}
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// endregion
+// region - xml
+
+type xmlCodec struct{}
+
+func XMLCodec() Codec {
+	return xmlCodec{}
+}
+func (xmlCodec) ContentType() string {
+	return ContentTypeXML
+}
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// endregion
+// region - form
+
+type formCodec struct{}
+
+// FormCodec encodes url.Values, maps and structs (see WithQuery for struct tags) as
+// "application/x-www-form-urlencoded" and decodes into pointers to url.Values,
+// map[string]string and map[string][]string.
+func FormCodec() Codec {
+	return formCodec{}
+}
+func (formCodec) ContentType() string {
+	return ContentTypeForm
+}
+func (formCodec) Encode(w io.Writer, v any) error {
+	var values url.Values
+	switch v := v.(type) {
+	case url.Values:
+		values = v
+	case map[string][]string:
+		values = v
+	case map[string]string:
+		values = url.Values{}
+		for k, s := range v {
+			values.Set(k, s)
+		}
+	default:
+		var err error
+		if values, err = encodeQuery(v); err != nil {
+			return fmt.Errorf("form codec: %w", err)
+		}
+	}
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
+func (formCodec) Decode(r io.Reader, v any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case *url.Values:
+		*v = values
+	case *map[string][]string:
+		*v = values
+	case *map[string]string:
+		m := make(map[string]string, len(values))
+		for k := range values {
+			m[k] = values.Get(k)
+		}
+		*v = m
+	default:
+		return fmt.Errorf("form codec: %w %T", ErrUnsupportedType, v)
+	}
+	return nil
+}
+
+// endregion
+// region - text
+
+type textCodec struct{}
+
+// TextCodec encodes strings, byte slices, encoding.TextMarshaler and fmt.Stringer values
+// as plain text and decodes into *string, *[]byte or encoding.TextUnmarshaler.
+func TextCodec() Codec {
+	return textCodec{}
+}
+func (textCodec) ContentType() string {
+	return ContentTypeText
+}
+func (textCodec) Encode(w io.Writer, v any) error {
+	var err error
+	switch v := v.(type) {
+	case string:
+		_, err = io.WriteString(w, v)
+	case []byte:
+		_, err = w.Write(v)
+	case encoding.TextMarshaler:
+		var b []byte
+		if b, err = v.MarshalText(); err == nil {
+			_, err = w.Write(b)
+		}
+	case fmt.Stringer:
+		_, err = io.WriteString(w, v.String())
+	default:
+		_, err = fmt.Fprint(w, v)
+	}
+	return err
+}
+func (textCodec) Decode(r io.Reader, v any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case *string:
+		*v = string(b)
+	case *[]byte:
+		*v = b
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(b)
+	default:
+		return fmt.Errorf("text codec: %w %T", ErrUnsupportedType, v)
+	}
+	return nil
+}
+
+// endregion
@@ -0,0 +1,79 @@
+package rc
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testXmlItem struct {
+	XMLName xml.Name `xml:"item"`
+	ID      int      `xml:"id"`
+	Name    string   `xml:"name"`
+}
+
+func TestCodecDefaultJson(t *testing.T) {
+	req, err := createTestClient().NewRequest(
+		WithMethod(http.MethodPost),
+		WithBody(testItem{ID: 1}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, ContentTypeJSON, req.Header.Get("Content-Type"))
+	b, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "{\"id\":1,\"name\":\"\"}\n", string(b))
+}
+func TestCodecRequestForm(t *testing.T) {
+	req, err := createTestClient().NewRequest(
+		WithMethod(http.MethodPost),
+		WithBody(map[string]string{"k1": "v 1", "k2": "v2"}),
+		WithBodyCodec(FormCodec()),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, ContentTypeForm, req.Header.Get("Content-Type"))
+	b, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "k1=v+1&k2=v2", string(b))
+}
+func TestCodecXmlRoundTrip(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, ContentTypeXML, r.Header.Get("Content-Type"))
+		var in testXmlItem
+		assert.NoError(t, xml.NewDecoder(r.Body).Decode(&in))
+		in.ID = 5
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		_ = xml.NewEncoder(w).Encode(in)
+	}, WithBasicOption(WithDefaultCodec(XMLCodec())))
+	item, _, err := Post[testXmlItem](context.Background(), c, testXmlItem{Name: "xml"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, item.ID)
+	assert.Equal(t, "xml", item.Name)
+}
+func TestCodecResponseByContentType(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/form":
+			w.Header().Set("Content-Type", ContentTypeForm)
+			_, _ = w.Write([]byte("a=1&b=2"))
+		case "/text":
+			w.Header().Set("Content-Type", ContentTypeText)
+			_, _ = w.Write([]byte("hello"))
+		case "/sniffed":
+			_, _ = w.Write([]byte(`{"id":3,"name":"sniffed"}`))
+		}
+	})
+	form, _, err := Get[url.Values](context.Background(), c, WithQueryPath("/form"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", form.Get("b"))
+
+	text, _, err := Get[string](context.Background(), c, WithQueryPath("/text"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", text)
+
+	item, _, err := Get[testItem](context.Background(), c, WithQueryPath("/sniffed"))
+	assert.NoError(t, err)
+	assert.Equal(t, testItem{ID: 3, Name: "sniffed"}, item)
+}
@@ -1,8 +1,197 @@
 package rc
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
 
 var (
 	ErrBaseUrlNotSet = errors.New("base url not set")
 	ErrNonNilContext = errors.New("context must be non-nil")
+	ErrClientClosed  = errors.New("client is closed")
+
+	ErrUnsupportedType = errors.New("unsupported type")
+	ErrBodyConflict    = errors.New("request body and multipart parts are mutually exclusive")
+
+	ErrBodyNotReplayable = errors.New("request body can't be replayed")
+	ErrPathTemplate      = errors.New("invalid path template")
 )
+
+// Sentinels to classify request errors with errors.Is (see also IsNotFound etc.). HTTPError
+// matches them by response status, TransportError matches ErrTransport and, for network
+// timeouts, ErrTimeout.
+var (
+	ErrNotFound     = errors.New("not found")       // 404
+	ErrUnauthorized = errors.New("unauthorized")    // 401
+	ErrConflict     = errors.New("conflict")        // 409
+	ErrServerError  = errors.New("server error")    // 5xx
+	ErrTimeout      = errors.New("timeout")         // 408, 504, network timeout
+	ErrTransport    = errors.New("transport error") // no response received
+)
+
+const (
+	// errorBodyLimit is max number of response body bytes kept by HTTPError
+	errorBodyLimit = 64 << 10
+	// errorSnippetLimit is max number of response body bytes included into error message
+	errorSnippetLimit = 256
+)
+
+// HTTPError describes non-2xx response. Body holds (at most 64 KiB of) response body,
+// Truncated is set if the body was longer. Problem holds RFC 7807 problem details if
+// response content type is "application/problem+json", and Result holds the body decoded
+// into error result type, if any (see WithErrorResult). Errors returned for specific statuses
+// (ErrResourceNotFound, ErrTooManyRequests, ErrServiceUnavailable) wrap HTTPError,
+// so it's always available via errors.As.
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+	Truncated  bool
+	Attempt    int
+	Problem    *ProblemDetails
+	Result     any
+}
+
+func newHTTPError(r *http.Response, attempt int) *HTTPError {
+	e := &HTTPError{
+		StatusCode: r.StatusCode,
+		Status:     r.Status,
+		Header:     r.Header,
+		Attempt:    attempt,
+	}
+	if r.Request != nil {
+		e.Method = r.Request.Method
+		e.URL = r.Request.URL.Redacted()
+	}
+	if r.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(r.Body, errorBodyLimit+1))
+		if len(body) > errorBodyLimit {
+			body = body[:errorBodyLimit]
+			e.Truncated = true
+		}
+		e.Body = body
+	}
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = http.StatusText(e.StatusCode)
+	}
+	msg := fmt.Sprintf("%s %s: status:[%d] %s", e.Method, e.URL, e.StatusCode, status)
+	if snippet := e.snippet(); snippet != "" {
+		msg += ": " + snippet
+	}
+	return msg
+}
+
+// snippet returns beginning of response body suitable for logging.
+func (e *HTTPError) snippet() string {
+	s := strings.Join(strings.Fields(string(e.Body)), " ")
+	if len(s) <= errorSnippetLimit {
+		return s
+	}
+	s = s[:errorSnippetLimit]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
+
+// Is makes HTTPError match status sentinels (ErrNotFound, ErrServerError, ...).
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError
+	case ErrTimeout:
+		return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// unwrapHTTPError is used by status specific errors to avoid returning typed nil.
+func unwrapHTTPError(e *HTTPError) error {
+	if e == nil {
+		return nil
+	}
+	return e
+}
+
+// TransportError is returned when request fails before response is received (DNS,
+// connection, TLS errors and so on); status returned with it is 0. Err is usually *url.Error.
+type TransportError struct {
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("transport error: %s", e.Err)
+}
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+func (e *TransportError) Is(target error) bool {
+	switch target {
+	case ErrTransport:
+		return true
+	case ErrTimeout:
+		return e.Timeout()
+	}
+	return false
+}
+
+// Timeout reports whether request timed out.
+func (e *TransportError) Timeout() bool {
+	var ne net.Error
+	return errors.As(e.Err, &ne) && ne.Timeout()
+}
+
+// region - predicates
+
+// IsNotFound reports whether err is caused by 404 response.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsUnauthorized reports whether err is caused by 401 response.
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsConflict reports whether err is caused by 409 response.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsServerError reports whether err is caused by 5xx response.
+func IsServerError(err error) bool {
+	return errors.Is(err, ErrServerError)
+}
+
+// IsTimeout reports whether request timed out: on the client side (network timeout or
+// context deadline) or on the server side (408 and 504 responses).
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsTransport reports whether request failed before response was received.
+func IsTransport(err error) bool {
+	return errors.Is(err, ErrTransport)
+}
+
+// endregion
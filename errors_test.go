@@ -0,0 +1,135 @@
+package rc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPError(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "r1")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":   "invalid
+name"}`))
+	})
+	_, res, err := Post[testItem](context.Background(), c, testItem{}, WithQueryPath("/items"))
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	var he *HTTPError
+	if assert.ErrorAs(t, err, &he) {
+		assert.Equal(t, http.MethodPost, he.Method)
+		assert.True(t, strings.HasSuffix(he.URL, "/items"))
+		assert.Equal(t, http.StatusBadRequest, he.StatusCode)
+		assert.Equal(t, "r1", he.Header.Get("X-Request-Id"))
+		assert.Equal(t, "{\"error\":   \"invalid\nname\"}", string(he.Body))
+		assert.False(t, he.Truncated)
+		assert.Equal(t, 1, he.Attempt)
+		assert.Contains(t, he.Error(), `status:[400] 400 Bad Request: {"error": "invalid name"}`)
+	}
+}
+func TestHTTPErrorBodyLimit(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(strings.Repeat("x", errorBodyLimit+10)))
+	})
+	_, _, err := Get[testItem](context.Background(), c)
+	var he *HTTPError
+	if assert.ErrorAs(t, err, &he) {
+		assert.Len(t, he.Body, errorBodyLimit)
+		assert.True(t, he.Truncated)
+		assert.True(t, strings.HasSuffix(he.Error(), strings.Repeat("x", errorSnippetLimit)+"..."))
+	}
+}
+func TestHTTPErrorWrapped(t *testing.T) {
+	handler, _ := failingHandler(2, http.StatusTooManyRequests, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("no such item"))
+	})
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(3)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+	)
+	_, _, err := Get[testItem](context.Background(), c)
+	assert.ErrorAs(t, err, &ErrResourceNotFound{})
+	var he *HTTPError
+	if assert.ErrorAs(t, err, &he) {
+		assert.Equal(t, http.StatusNotFound, he.StatusCode)
+		assert.Equal(t, "no such item", string(he.Body))
+		assert.Equal(t, 3, he.Attempt)
+	}
+
+	// locally generated errors have no response
+	assert.False(t, errors.As(ErrTooManyRequests{Delay: time.Second}, &he))
+}
+func TestErrorPredicates(t *testing.T) {
+	tests := []struct {
+		status    int
+		predicate func(error) bool
+	}{
+		{http.StatusNotFound, IsNotFound},
+		{http.StatusUnauthorized, IsUnauthorized},
+		{http.StatusConflict, IsConflict},
+		{http.StatusInternalServerError, IsServerError},
+		{http.StatusBadGateway, IsServerError},
+		{http.StatusGatewayTimeout, IsTimeout},
+		{http.StatusRequestTimeout, IsTimeout},
+	}
+	predicates := []func(error) bool{IsNotFound, IsUnauthorized, IsConflict, IsServerError, IsTimeout, IsTransport}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+			_, _, err := Get[testItem](context.Background(), c)
+			matched := 0
+			for _, p := range predicates {
+				if p(err) {
+					matched++
+				}
+			}
+			assert.True(t, tt.predicate(err))
+			// only 504 is both server error & timeout
+			if tt.status == http.StatusGatewayTimeout {
+				assert.Equal(t, 2, matched)
+			} else {
+				assert.Equal(t, 1, matched)
+			}
+		})
+	}
+}
+func TestTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(okHandler))
+	srv.Close()
+	c, err := CreateClient(WithBasicOption(WithBaseUrl(srv.URL)))
+	assert.NoError(t, err)
+	_, res, err := Get[testItem](context.Background(), c)
+	assert.Equal(t, 0, res.StatusCode)
+	assert.True(t, IsTransport(err))
+	assert.False(t, IsTimeout(err))
+	var te *TransportError
+	if assert.ErrorAs(t, err, &te) {
+		var ue *url.Error
+		assert.ErrorAs(t, te, &ue)
+	}
+
+	slow := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}, WithBasicOption(WithHttpClient(&http.Client{Timeout: 10 * time.Millisecond})))
+	_, res, err = Get[testItem](context.Background(), slow)
+	assert.Equal(t, 0, res.StatusCode)
+	assert.True(t, IsTransport(err))
+	assert.True(t, IsTimeout(err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, res, err = Get[testItem](ctx, slow)
+	assert.Equal(t, 0, res.StatusCode)
+	assert.True(t, IsTimeout(err))
+}
@@ -0,0 +1,80 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+)
+
+// region - result
+
+// Result describes the outcome of a typed request made through one of the
+// generic helpers (Get, Post, Send, ...). Response body is already consumed
+// and closed when Result is returned.
+type Result struct {
+	Response   *Response
+	StatusCode int
+}
+
+// endregion
+// region - typed execution
+
+// Do executes req with client c and decodes response into a new value of type T.
+func Do[T any](ctx context.Context, c Client, req *http.Request) (T, *Result, error) {
+	var v T
+	resp, status, err := c.Do(ctx, req, &v)
+	return v, &Result{Response: resp, StatusCode: status}, err
+}
+
+// Send builds request with given method & body through c.NewRequest, executes it
+// and decodes response into a new value of type Resp. Nil body (including typed nil
+// pointer) is not sent.
+func Send[Req, Resp any](ctx context.Context, c Client, method string, body Req, options ...RequestOption) (Resp, *Result, error) {
+	return send[Resp](ctx, c, method, body, options...)
+}
+
+func Get[T any](ctx context.Context, c Client, options ...RequestOption) (T, *Result, error) {
+	return send[T](ctx, c, http.MethodGet, nil, options...)
+}
+func Head(ctx context.Context, c Client, options ...RequestOption) (*Result, error) {
+	_, res, err := send[struct{}](ctx, c, http.MethodHead, nil, options...)
+	return res, err
+}
+func Delete[T any](ctx context.Context, c Client, options ...RequestOption) (T, *Result, error) {
+	return send[T](ctx, c, http.MethodDelete, nil, options...)
+}
+func Post[T any](ctx context.Context, c Client, body any, options ...RequestOption) (T, *Result, error) {
+	return send[T](ctx, c, http.MethodPost, body, options...)
+}
+func Put[T any](ctx context.Context, c Client, body any, options ...RequestOption) (T, *Result, error) {
+	return send[T](ctx, c, http.MethodPut, body, options...)
+}
+func Patch[T any](ctx context.Context, c Client, body any, options ...RequestOption) (T, *Result, error) {
+	return send[T](ctx, c, http.MethodPatch, body, options...)
+}
+
+func send[T any](ctx context.Context, c Client, method string, body any, options ...RequestOption) (T, *Result, error) {
+	opts := make([]RequestOption, 0, len(options)+2)
+	opts = append(opts, options...)
+	opts = append(opts, WithMethod(method))
+	if !isNilBody(body) {
+		opts = append(opts, WithBody(body))
+	}
+	req, err := c.NewRequest(opts...)
+	if err != nil {
+		var v T
+		return v, nil, err
+	}
+	return Do[T](ctx, c, req)
+}
+
+// isNilBody reports whether body is nil or nil pointer (typed nil wrapped into any).
+func isNilBody(body any) bool {
+	if body == nil {
+		return true
+	}
+	rv := reflect.ValueOf(body)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// endregion
@@ -0,0 +1,84 @@
+package rc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func createTestServerClient(t *testing.T, handler http.HandlerFunc, options ...RestClientOption) Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	options = append(options, WithBasicOption(WithBaseUrl(srv.URL)))
+	c, err := CreateClient(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestGenericGet(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/items/1", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(testItem{ID: 1, Name: "first"})
+	})
+	item, res, err := Get[testItem](context.Background(), c, WithQueryPath("/items/1"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, testItem{ID: 1, Name: "first"}, item)
+}
+func TestGenericSend(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		var in testItem
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		in.ID = 2
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(in)
+	})
+	item, res, err := Send[testItem, testItem](context.Background(), c, http.MethodPut, testItem{Name: "second"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, testItem{ID: 2, Name: "second"}, item)
+}
+func TestGenericEmptyBody(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	item, res, err := Delete[*testItem](context.Background(), c, WithQueryPath("/items/1"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Nil(t, item)
+}
+func TestGenericNilBody(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Empty(t, body)
+		assert.Empty(t, r.Header.Get("Content-Type"))
+	})
+	var item *testItem
+	_, _, err := Send[*testItem, string](context.Background(), c, http.MethodPost, item)
+	assert.NoError(t, err)
+	_, _, err = Post[string](context.Background(), c, item)
+	assert.NoError(t, err)
+}
+func TestGenericError(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	_, res, err := Get[testItem](context.Background(), c, WithQueryPath("/items/3"))
+	assert.ErrorAs(t, err, &ErrResourceNotFound{})
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,58 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+)
+
+// BeforeRequestHook is called right before request is sent. It may mutate request
+// (e.g. set headers or sign it); returned error aborts the request. With retries,
+// hooks run once per attempt on a fresh copy of request.
+type BeforeRequestHook func(ctx context.Context, req *http.Request) error
+
+// AfterResponseHook is called for every received response (including non-2xx ones)
+// before status is checked and body is decoded. Returned error fails the request.
+type AfterResponseHook func(ctx context.Context, resp *Response) error
+
+// ErrorHook observes request errors: transport errors, non-2xx statuses and hook
+// failures. Status is the one returned by BareDo.
+type ErrorHook func(ctx context.Context, req *http.Request, status int, err error)
+
+type hooks struct {
+	before  []BeforeRequestHook
+	after   []AfterResponseHook
+	onError []ErrorHook
+}
+
+// merge returns client hooks followed by request ones.
+func (h hooks) merge(other hooks) hooks {
+	return hooks{
+		before:  append(h.before[:len(h.before):len(h.before)], other.before...),
+		after:   append(h.after[:len(h.after):len(h.after)], other.after...),
+		onError: append(h.onError[:len(h.onError):len(h.onError)], other.onError...),
+	}
+}
+
+func (h hooks) beforeRequest(ctx context.Context, req *http.Request) error {
+	for _, hook := range h.before {
+		if err := hook(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h hooks) afterResponse(ctx context.Context, resp *Response) error {
+	for _, hook := range h.after {
+		if err := hook(ctx, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h hooks) error(ctx context.Context, req *http.Request, status int, err error) {
+	for _, hook := range h.onError {
+		hook(ctx, req, status, err)
+	}
+}
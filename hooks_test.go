@@ -0,0 +1,116 @@
+package rc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooksOrder(t *testing.T) {
+	var trace []string
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "client", r.Header.Get("X-Client"))
+		assert.Equal(t, "request", r.Header.Get("X-Request"))
+		w.WriteHeader(http.StatusOK)
+	},
+		WithBasicOption(WithBasicBeforeRequest(func(_ context.Context, req *http.Request) error {
+			trace = append(trace, "client before")
+			req.Header.Set("X-Client", "client")
+			return nil
+		})),
+		WithBasicOption(WithBasicAfterResponse(func(_ context.Context, resp *Response) error {
+			trace = append(trace, "client after")
+			return nil
+		})),
+	)
+	_, _, err := Get[string](context.Background(), c,
+		WithBeforeRequest(func(_ context.Context, req *http.Request) error {
+			trace = append(trace, "request before")
+			req.Header.Set("X-Request", "request")
+			return nil
+		}),
+		WithAfterResponse(func(_ context.Context, resp *Response) error {
+			trace = append(trace, "request after")
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"client before", "request before", "client after", "request after"}, trace)
+}
+func TestHooksSeeErrorResponse(t *testing.T) {
+	var seen, observed int
+	var observedErr error
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	},
+		WithBasicOption(WithBasicOnError(func(_ context.Context, _ *http.Request, status int, err error) {
+			observed = status
+			observedErr = err
+		})),
+	)
+	_, _, err := Get[string](context.Background(), c,
+		WithAfterResponse(func(_ context.Context, resp *Response) error {
+			seen = resp.StatusCode
+			return nil
+		}),
+	)
+	assert.ErrorAs(t, err, &ErrResourceNotFound{})
+	assert.Equal(t, http.StatusNotFound, seen)
+	assert.Equal(t, http.StatusNotFound, observed)
+	assert.ErrorAs(t, observedErr, &ErrResourceNotFound{})
+}
+func TestHooksAbortRequest(t *testing.T) {
+	errAbort := errors.New("abort")
+	calls := 0
+	var observedErr error
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+	_, _, err := Get[string](context.Background(), c,
+		WithBeforeRequest(func(context.Context, *http.Request) error {
+			return errAbort
+		}),
+		WithOnError(func(_ context.Context, _ *http.Request, _ int, err error) {
+			observedErr = err
+		}),
+	)
+	assert.ErrorIs(t, err, errAbort)
+	assert.ErrorIs(t, observedErr, errAbort)
+	assert.Equal(t, 0, calls)
+
+	_, _, err = Get[string](context.Background(), c,
+		WithAfterResponse(func(context.Context, *Response) error {
+			return errAbort
+		}),
+	)
+	assert.ErrorIs(t, err, errAbort)
+	assert.Equal(t, 1, calls)
+}
+func TestHooksRunPerAttempt(t *testing.T) {
+	handler, calls := failingHandler(2, http.StatusInternalServerError, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []string{"3"}, r.Header.Values("X-Attempt"))
+		w.WriteHeader(http.StatusOK)
+	})
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(3)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+	)
+	failures := 0
+	_, _, err := Get[string](context.Background(), c,
+		WithBeforeRequest(func(ctx context.Context, req *http.Request) error {
+			// header is added to a fresh request copy on each attempt
+			req.Header.Add("X-Attempt", string(rune('0'+AttemptFromContext(ctx))))
+			return nil
+		}),
+		WithOnError(func(context.Context, *http.Request, int, error) {
+			failures++
+		}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, 2, failures)
+}
@@ -0,0 +1,264 @@
+package rc
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// region - token bucket
+
+// tokenBucket is goroutine-safe token bucket, refilled with refillTokens every refillInterval
+// (up to maxTokens). Refill is calculated on access, so bucket does not need background
+// goroutine and can't be starved by cancellation of any particular request context.
+type tokenBucket struct {
+	mu             sync.Mutex
+	clock          Clock
+	maxTokens      int
+	refillTokens   int
+	refillInterval time.Duration
+	tokens         int
+	lastRefill     time.Time
+	lastUsed       time.Time
+}
+
+func newTokenBucket(maxTokens, refillTokens int, refillInterval time.Duration, clock Clock) *tokenBucket {
+	return &tokenBucket{
+		clock:          clock,
+		maxTokens:      maxTokens,
+		refillTokens:   refillTokens,
+		refillInterval: refillInterval,
+		tokens:         maxTokens,
+		lastRefill:     clock.Now(),
+		lastUsed:       clock.Now(),
+	}
+}
+
+// take takes single token; if there are no tokens left, returns false and time to wait for refill.
+func (b *tokenBucket) take() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	b.lastUsed = now
+	b.refill(now)
+	if b.tokens <= 0 {
+		return false, b.delay(now)
+	}
+	b.tokens--
+	return true, 0
+}
+
+// penalize reduces available tokens after upstream reported too many requests,
+// returns time to wait before next call.
+func (b *tokenBucket) penalize() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	b.refill(now)
+	b.tokens /= 5 // чтобы не в ноль сбрасывать; чтобы по возможности ждать не весь refillInterval
+	return b.delay(now) / 2
+}
+
+// sync adjusts bucket to rate limit state reported by upstream: tokens are reduced to the
+// number of requests upstream still allows and, if upstream quota is exhausted, next
+// refill is postponed until upstream resets it.
+func (b *tokenBucket) sync(state rateLimitState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	b.refill(now)
+	if state.limit >= 0 && state.limit < b.tokens {
+		b.tokens = state.limit
+	}
+	if state.remaining >= 0 && state.remaining < b.tokens {
+		b.tokens = state.remaining
+	}
+	if b.tokens <= 0 && state.reset >= 0 {
+		if next := now.Add(state.reset); next.After(b.lastRefill.Add(b.refillInterval)) {
+			b.lastRefill = next.Add(-b.refillInterval)
+		}
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if b.refillInterval <= 0 {
+		b.tokens = b.maxTokens
+		return
+	}
+	n := int(now.Sub(b.lastRefill) / b.refillInterval)
+	if n <= 0 {
+		return
+	}
+	b.lastRefill = b.lastRefill.Add(time.Duration(n) * b.refillInterval)
+	if b.tokens < b.maxTokens {
+		b.tokens = min(b.maxTokens, b.tokens+n*b.refillTokens)
+	}
+}
+
+func (b *tokenBucket) idleSince() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastUsed
+}
+
+// delay calculates time till next refill (with small margin)
+func (b *tokenBucket) delay(now time.Time) time.Duration {
+	d := b.refillInterval - now.Sub(b.lastRefill) + 100*time.Millisecond
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// endregion
+// region - keyed limiter
+
+// ThrottleLimit configures token bucket of ThrottleClient; zero fields inherit client defaults.
+type ThrottleLimit struct {
+	MaxTokens      int
+	RefillTokens   int
+	RefillInterval time.Duration
+}
+
+// inherit returns limit with zero fields taken from defaults.
+func (l ThrottleLimit) inherit(defaults ThrottleLimit) ThrottleLimit {
+	if l.MaxTokens == 0 {
+		l.MaxTokens = defaults.MaxTokens
+	}
+	if l.RefillTokens == 0 {
+		l.RefillTokens = defaults.RefillTokens
+	}
+	if l.RefillInterval == 0 {
+		l.RefillInterval = defaults.RefillInterval
+	}
+	return l
+}
+
+// refillTime returns time needed to refill empty bucket.
+func (l ThrottleLimit) refillTime() time.Duration {
+	n := (l.MaxTokens + l.RefillTokens - 1) / l.RefillTokens
+	return time.Duration(n) * l.RefillInterval
+}
+
+func (l ThrottleLimit) validate() error {
+	if l.MaxTokens <= 0 {
+		return fmt.Errorf("invalid max tokens: %d", l.MaxTokens)
+	}
+	if l.RefillTokens <= 0 {
+		return fmt.Errorf("invalid refill tokens: %d", l.RefillTokens)
+	}
+	if l.RefillInterval <= 0 {
+		return fmt.Errorf("invalid refill interval: %s", l.RefillInterval)
+	}
+	return nil
+}
+
+// ThrottleKeyFunc selects token bucket for request. Requests with empty key share default bucket.
+type ThrottleKeyFunc func(req *http.Request) string
+
+// ThrottleKeyByHost limits requests per target host.
+func ThrottleKeyByHost() ThrottleKeyFunc {
+	return func(req *http.Request) string {
+		return req.URL.Host
+	}
+}
+
+// ThrottleKeyByHeader limits requests per value of given header (e.g. tenant ID).
+func ThrottleKeyByHeader(name string) ThrottleKeyFunc {
+	return func(req *http.Request) string {
+		return req.Header.Get(name)
+	}
+}
+
+// ThrottleKeyByRoute limits requests per route pattern. Pattern is "[METHOD ]/path", path
+// syntax is that of path.Match (e.g. "GET /users/*"). The first matching pattern is used as
+// a key; requests not matching any pattern use default bucket.
+func ThrottleKeyByRoute(patterns ...string) ThrottleKeyFunc {
+	return func(req *http.Request) string {
+		for _, p := range patterns {
+			method, pattern, found := strings.Cut(p, " ")
+			if !found {
+				method, pattern = "", p
+			}
+			if method != "" && method != req.Method {
+				continue
+			}
+			if ok, _ := path.Match(pattern, req.URL.Path); ok {
+				return p
+			}
+		}
+		return ""
+	}
+}
+
+// keyedLimiter keeps token bucket per key. Buckets not used for idleTTL are evicted.
+type keyedLimiter struct {
+	mu        sync.Mutex
+	clock     Clock
+	keyFunc   ThrottleKeyFunc
+	defaults  ThrottleLimit
+	limits    map[string]ThrottleLimit
+	buckets   map[string]*tokenBucket
+	idleTTL   time.Duration
+	lastSweep time.Time
+}
+
+func newKeyedLimiter(keyFunc ThrottleKeyFunc, defaults ThrottleLimit, limits map[string]ThrottleLimit, idleTTL time.Duration, clock Clock) *keyedLimiter {
+	return &keyedLimiter{
+		clock:     clock,
+		keyFunc:   keyFunc,
+		defaults:  defaults,
+		limits:    limits,
+		buckets:   make(map[string]*tokenBucket),
+		idleTTL:   idleTTL,
+		lastSweep: clock.Now(),
+	}
+}
+
+func (l *keyedLimiter) bucket(req *http.Request) *tokenBucket {
+	key := ""
+	if l.keyFunc != nil {
+		key = l.keyFunc(req)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep()
+	b, ok := l.buckets[key]
+	if !ok {
+		limit, ok := l.limits[key]
+		if !ok {
+			limit = l.defaults
+		}
+		b = newTokenBucket(limit.MaxTokens, limit.RefillTokens, limit.RefillInterval, l.clock)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// sweep evicts idle buckets; runs at most once per idleTTL
+func (l *keyedLimiter) sweep() {
+	if l.idleTTL <= 0 {
+		return
+	}
+	now := l.clock.Now()
+	if now.Sub(l.lastSweep) < l.idleTTL {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.idleSince()) >= l.idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *keyedLimiter) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// endregion
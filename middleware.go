@@ -0,0 +1,91 @@
+package rc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+var errHandlerClient = errors.New("handler does not build requests")
+
+// region - handler & middleware
+
+// Handler executes prepared request; it has the signature of Client.BareDo.
+type Handler func(ctx context.Context, req *http.Request) (*Response, int, error)
+
+// Middleware wraps Handler with additional behaviour (auth, logging, metrics, ...).
+type Middleware func(next Handler) Handler
+
+// Chain returns client which executes requests of given client through middlewares.
+// The first middleware is the outermost one, i.e. it sees the request first.
+func Chain(client Client, middlewares ...Middleware) Client {
+	h := Handler(client.BareDo)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return &chainClient{
+		layer:   layer{client},
+		handler: h,
+	}
+}
+
+type chainClient struct {
+	layer
+	handler Handler
+}
+
+func (c *chainClient) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, int, error) {
+	return do(ctx, c.BareDo, req, v)
+}
+func (c *chainClient) BareDo(ctx context.Context, req *http.Request) (*Response, int, error) {
+	if ctx == nil {
+		return nil, http.StatusInternalServerError, ErrNonNilContext
+	}
+	return c.handler(ctx, req)
+}
+
+// Close closes wrapped client if it implements io.Closer.
+func (c *chainClient) Close() error {
+	return closeClient(c.client)
+}
+
+// endregion
+// region - client layers as middleware
+
+// handlerClient adapts Handler to Client interface, so that client layers can wrap it.
+// Layers only execute requests, so request building is not supported.
+type handlerClient Handler
+
+func (h handlerClient) GetBaseURL() *url.URL {
+	return nil
+}
+func (h handlerClient) NewRequest(...RequestOption) (*http.Request, error) {
+	return nil, errHandlerClient
+}
+func (h handlerClient) Do(ctx context.Context, req *http.Request, v interface{}) (*Response, int, error) {
+	return do(ctx, h.BareDo, req, v)
+}
+func (h handlerClient) BareDo(ctx context.Context, req *http.Request) (*Response, int, error) {
+	return h(ctx, req)
+}
+
+// layerMiddleware turns client layer constructor into Middleware. Options are validated
+// right away; new layer is created each time middleware is applied.
+func layerMiddleware[O any](factory func(Client, ...O) (Client, error), options []O) (Middleware, error) {
+	if _, err := factory(handlerClient(nil), options...); err != nil {
+		return nil, err
+	}
+	return func(next Handler) Handler {
+		c, err := factory(handlerClient(next), options...)
+		if err != nil {
+			// can't happen, as options are validated already
+			return func(context.Context, *http.Request) (*Response, int, error) {
+				return nil, 0, err
+			}
+		}
+		return c.BareDo
+	}, nil
+}
+
+// endregion
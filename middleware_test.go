@@ -0,0 +1,77 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recordingMiddleware(name string, mu *sync.Mutex, trace *[]string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *http.Request) (*Response, int, error) {
+			mu.Lock()
+			*trace = append(*trace, name+">")
+			mu.Unlock()
+			res, status, err := next(ctx, req)
+			mu.Lock()
+			*trace = append(*trace, "<"+name)
+			mu.Unlock()
+			return res, status, err
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var mu sync.Mutex
+	var trace []string
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Auth"))
+		_, _ = w.Write([]byte(`{"id":1}`))
+	},
+		WithMiddleware(
+			recordingMiddleware("outer", &mu, &trace),
+			func(next Handler) Handler {
+				return func(ctx context.Context, req *http.Request) (*Response, int, error) {
+					req.Header.Set("X-Auth", "secret")
+					return next(ctx, req)
+				}
+			},
+		),
+		WithMiddleware(recordingMiddleware("inner", &mu, &trace)),
+	)
+	item, _, err := Get[testItem](context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, item.ID)
+	assert.Equal(t, []string{"outer>", "inner>", "<inner", "<outer"}, trace)
+}
+func TestMiddlewareBuiltinLayers(t *testing.T) {
+	var mu sync.Mutex
+	var trace []string
+	handler, calls := failingHandler(2, http.StatusInternalServerError, okHandler)
+	retry, err := RetryMiddleware(WithMaxAttempts(3), WithRetryDelay(time.Millisecond))
+	assert.NoError(t, err)
+	throttle, err := ThrottleMiddleware(WithMaxTokens(10))
+	assert.NoError(t, err)
+	c := createTestServerClient(t, handler,
+		WithMiddleware(
+			recordingMiddleware("metrics", &mu, &trace),
+			retry,
+			recordingMiddleware("attempt", &mu, &trace),
+			throttle,
+		),
+	)
+	_, _, err = Get[testItem](context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, []string{"metrics>", "attempt>", "<attempt", "attempt>", "<attempt", "attempt>", "<attempt", "<metrics"}, trace)
+}
+func TestMiddlewareInvalidLayer(t *testing.T) {
+	_, err := BulkheadMiddleware(WithMaxConcurrent(0))
+	assert.Error(t, err)
+	_, err = OAuth2Middleware()
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package rc
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+type multipartPart struct {
+	name        string
+	filename    string
+	contentType string
+	value       string
+	reader      io.Reader
+}
+
+// multipartBody streams multipart/form-data request body through a pipe. Parts are
+// written lazily by a goroutine started on first Read, so file contents are never
+// buffered in memory as a whole, and nothing is leaked if request is never sent.
+type multipartBody struct {
+	parts  []multipartPart
+	writer *multipart.Writer
+	pr     *io.PipeReader
+	pw     *io.PipeWriter
+	once   sync.Once
+	done   chan struct{}
+}
+
+func newMultipartBody(parts []multipartPart) *multipartBody {
+	pr, pw := io.Pipe()
+	return &multipartBody{
+		parts:  parts,
+		writer: multipart.NewWriter(pw),
+		pr:     pr,
+		pw:     pw,
+		done:   make(chan struct{}),
+	}
+}
+
+func (b *multipartBody) ContentType() string {
+	return b.writer.FormDataContentType()
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		go b.write()
+	})
+	return b.pr.Read(p)
+}
+
+func (b *multipartBody) Close() error {
+	return b.pr.Close()
+}
+
+// stop closes body and waits until writer goroutine (if any) is done with part readers.
+func (b *multipartBody) stop() {
+	_ = b.pr.Close()
+	b.once.Do(func() {
+		close(b.done)
+	})
+	<-b.done
+}
+
+// replayer returns request GetBody function if all file parts are backed by io.Seeker
+// (e.g. *os.File), or nil otherwise. Each call rewinds readers to their initial offsets
+// and creates new body with the same boundary.
+func (b *multipartBody) replayer() func() (io.ReadCloser, error) {
+	offsets := make([]int64, len(b.parts))
+	for i, part := range b.parts {
+		if part.reader == nil {
+			continue
+		}
+		s, ok := part.reader.(io.Seeker)
+		if !ok {
+			return nil
+		}
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil
+		}
+		offsets[i] = offset
+	}
+	boundary := b.writer.Boundary()
+	var mu sync.Mutex
+	last := b
+	return func() (io.ReadCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		last.stop()
+		for i, part := range b.parts {
+			if part.reader == nil {
+				continue
+			}
+			if _, err := part.reader.(io.Seeker).Seek(offsets[i], io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		body := newMultipartBody(b.parts)
+		if err := body.writer.SetBoundary(boundary); err != nil {
+			return nil, err
+		}
+		last = body
+		return body, nil
+	}
+}
+
+func (b *multipartBody) write() {
+	defer close(b.done)
+	var err error
+	for _, part := range b.parts {
+		if err = b.writePart(part); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = b.writer.Close()
+	}
+	_ = b.pw.CloseWithError(err)
+}
+
+func (b *multipartBody) writePart(part multipartPart) error {
+	if part.reader == nil {
+		return b.writer.WriteField(part.name, part.value)
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(part.name), escapeQuotes(part.filename)))
+	h.Set("Content-Type", part.contentType)
+	w, err := b.writer.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, part.reader)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,54 @@
+package rc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "value", r.FormValue("field"))
+		f, h, err := r.FormFile("file")
+		if assert.NoError(t, err) {
+			defer f.Close()
+			b, _ := io.ReadAll(f)
+			assert.Equal(t, "doc.txt", h.Filename)
+			assert.Equal(t, "text/plain", h.Header.Get("Content-Type"))
+			assert.Equal(t, "file content", string(b))
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	req, err := c.NewRequest(
+		WithMethod(http.MethodPost),
+		WithQueryPath("/upload"),
+		WithMultipartField("field", "value"),
+		WithMultipartFileType("file", "doc.txt", "text/plain", strings.NewReader("file content")),
+	)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data; boundary="))
+	_, st, err := c.Do(context.Background(), req, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, st)
+}
+func TestMultipartBodyConflict(t *testing.T) {
+	_, err := createTestClient().NewRequest(
+		WithBody("body"),
+		WithMultipartField("field", "value"),
+	)
+	assert.ErrorIs(t, err, ErrBodyConflict)
+}
+func TestMultipartBodyCloseBeforeRead(t *testing.T) {
+	req, err := createTestClient().NewRequest(
+		WithMultipartFile("file", "a.bin", strings.NewReader("data")),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, req.Body.Close())
+	_, err = req.Body.Read(make([]byte, 8))
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
@@ -1,7 +1,9 @@
 package rc
 
 import (
+	"fmt"
 	"go.slink.ws/logging"
+	"io"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -66,6 +68,80 @@ func WithBasicLogger(value logging.Logger) BasicClientOption {
 	return &basicLogger{value}
 }
 
+type basicCodec struct {
+	value       Codec
+	makeDefault bool
+}
+
+func (o *basicCodec) Apply(client *BasicClient) {
+	client.codecs.register(o.value)
+	if o.makeDefault {
+		client.codec = o.value
+	}
+}
+
+// WithCodec registers codec used to decode responses of codec's content type.
+func WithCodec(value Codec) BasicClientOption {
+	return &basicCodec{value: value}
+}
+
+// WithDefaultCodec registers codec and makes it default one for request bodies.
+func WithDefaultCodec(value Codec) BasicClientOption {
+	return &basicCodec{value: value, makeDefault: true}
+}
+
+type basicHooks struct {
+	value hooks
+}
+
+func (o *basicHooks) Apply(client *BasicClient) {
+	client.hooks = client.hooks.merge(o.value)
+}
+
+// WithBasicBeforeRequest registers hook called before each request is sent.
+func WithBasicBeforeRequest(value BeforeRequestHook) BasicClientOption {
+	return &basicHooks{hooks{before: []BeforeRequestHook{value}}}
+}
+
+// WithBasicAfterResponse registers hook called for each response before it's checked & decoded.
+func WithBasicAfterResponse(value AfterResponseHook) BasicClientOption {
+	return &basicHooks{hooks{after: []AfterResponseHook{value}}}
+}
+
+// WithBasicOnError registers hook called for each failed request.
+func WithBasicOnError(value ErrorHook) BasicClientOption {
+	return &basicHooks{hooks{onError: []ErrorHook{value}}}
+}
+
+type basicAuth struct {
+	value CredentialsProvider
+}
+
+func (o *basicAuth) Apply(client *BasicClient) {
+	client.auth = o.value
+}
+
+// WithAuth sets credentials provider used to authenticate each request (see BearerToken,
+// BasicAuth, APIKeyHeader, APIKeyQuery and DynamicCredentials).
+func WithAuth(value CredentialsProvider) BasicClientOption {
+	return &basicAuth{value}
+}
+
+type basicErrorResult struct {
+	value func() any
+}
+
+func (o *basicErrorResult) Apply(client *BasicClient) {
+	client.errorResult = o.value
+}
+
+// WithBasicErrorResult sets factory of values non-2xx response bodies are decoded into
+// (e.g. func() any { return &ApiError{} }). Decoded value is available as HTTPError.Result
+// (see ErrorResult); WithErrorResult overrides it for single request.
+func WithBasicErrorResult(value func() any) BasicClientOption {
+	return &basicErrorResult{value}
+}
+
 func AddMissingBasicClientOption(opts []BasicClientOption, option BasicClientOption) []BasicClientOption {
 	missingOptionType := reflect.TypeOf(option)
 	for _, opt := range opts {
@@ -102,12 +178,344 @@ func WithRefillInterval(value time.Duration) ThrottleClientOption {
 		client.refillInterval = value
 	}
 }
+func WithThrottleMode(value ThrottleMode) ThrottleClientOption {
+	return func(client *ThrottleClient) {
+		client.mode = value
+	}
+}
+
+// WithThrottleMaxWait limits time request may wait for token in ThrottleWait mode;
+// if expected wait is longer, request fails with ErrTooManyRequests right away.
+func WithThrottleMaxWait(value time.Duration) ThrottleClientOption {
+	return func(client *ThrottleClient) {
+		client.maxWait = value
+	}
+}
+
+// WithThrottleKey makes ThrottleClient keep separate token bucket per key returned by keyFunc.
+// Buckets are configured with WithThrottleKeyLimit or with default limits otherwise.
+func WithThrottleKey(keyFunc ThrottleKeyFunc) ThrottleClientOption {
+	return func(client *ThrottleClient) {
+		client.keyFunc = keyFunc
+	}
+}
+
+// WithThrottleKeyLimit sets token bucket limits for key; zero fields of limit are taken
+// from client defaults (see WithMaxTokens, WithRefillTokens, WithRefillInterval).
+func WithThrottleKeyLimit(key string, limit ThrottleLimit) ThrottleClientOption {
+	return func(client *ThrottleClient) {
+		if client.limits == nil {
+			client.limits = make(map[string]ThrottleLimit)
+		}
+		client.limits[key] = limit
+	}
+}
+
+// WithThrottleIdleTTL evicts token buckets of keys not used for given time. By default, with
+// WithThrottleKey, buckets are evicted after twice the time needed to refill them completely;
+// negative value disables eviction.
+func WithThrottleIdleTTL(value time.Duration) ThrottleClientOption {
+	return func(client *ThrottleClient) {
+		client.idleTTL = value
+	}
+}
+
+// WithAdaptiveThrottle makes ThrottleClient resynchronize its token bucket with upstream
+// rate limit headers of each successful response. If no header sets are given,
+// DefaultRateLimitHeaders are used; IETF "RateLimit" header is always recognized.
+func WithAdaptiveThrottle(headers ...RateLimitHeaders) ThrottleClientOption {
+	return func(client *ThrottleClient) {
+		client.adaptive = true
+		if len(headers) == 0 {
+			headers = DefaultRateLimitHeaders()
+		}
+		client.rateLimitHeaders = headers
+	}
+}
+func WithThrottleClock(value Clock) ThrottleClientOption {
+	return func(client *ThrottleClient) {
+		client.clock = value
+	}
+}
 func WithThrottleLogger(value logging.Logger) ThrottleClientOption {
 	return func(client *ThrottleClient) {
 		client.logger = value
 	}
 }
 
+// endregion
+// region - bulkhead client options
+
+type BulkheadClientOption func(*BulkheadClient)
+
+// WithMaxConcurrent sets max number of requests executed concurrently.
+func WithMaxConcurrent(value int) BulkheadClientOption {
+	return func(client *BulkheadClient) {
+		client.maxConcurrent = value
+	}
+}
+
+// WithQueueLength sets max number of requests waiting for free slot; requests
+// above that are rejected with ErrBulkheadFull. By default, there is no queue.
+func WithQueueLength(value int) BulkheadClientOption {
+	return func(client *BulkheadClient) {
+		client.queueLength = value
+	}
+}
+
+// WithQueueTimeout limits time request may wait in queue.
+func WithQueueTimeout(value time.Duration) BulkheadClientOption {
+	return func(client *BulkheadClient) {
+		client.queueTimeout = value
+	}
+}
+func WithBulkheadClock(value Clock) BulkheadClientOption {
+	return func(client *BulkheadClient) {
+		client.clock = value
+	}
+}
+func WithBulkheadLogger(value logging.Logger) BulkheadClientOption {
+	return func(client *BulkheadClient) {
+		client.logger = value
+	}
+}
+
+// endregion
+// region - circuit breaker client options
+
+type CircuitBreakerClientOption func(*CircuitBreakerClient)
+
+// WithFailureThreshold opens circuit after given number of consecutive failures.
+func WithFailureThreshold(value int) CircuitBreakerClientOption {
+	return func(client *CircuitBreakerClient) {
+		client.failureThreshold = value
+	}
+}
+
+// WithFailureRatio opens circuit when failure ratio over rolling window reaches given value,
+// provided there were at least minRequests requests in the window. Replaces consecutive
+// failures threshold.
+func WithFailureRatio(ratio float64, window time.Duration, minRequests int) CircuitBreakerClientOption {
+	return func(client *CircuitBreakerClient) {
+		client.failureRatio = ratio
+		client.window = window
+		client.minRequests = minRequests
+	}
+}
+
+// WithOpenDuration sets time circuit stays open before letting probe requests through.
+func WithOpenDuration(value time.Duration) CircuitBreakerClientOption {
+	return func(client *CircuitBreakerClient) {
+		client.openDuration = value
+	}
+}
+
+// WithHalfOpenProbes sets number of probe requests in half-open state; circuit is closed
+// when all of them succeed.
+func WithHalfOpenProbes(value int) CircuitBreakerClientOption {
+	return func(client *CircuitBreakerClient) {
+		client.halfOpenProbes = value
+	}
+}
+
+// WithFailurePredicate sets function deciding which outcomes count as failures
+// (see DefaultCircuitFailure). Requests canceled by caller are not recorded at all.
+func WithFailurePredicate(value func(status int, err error) bool) CircuitBreakerClientOption {
+	return func(client *CircuitBreakerClient) {
+		client.isFailure = value
+	}
+}
+
+// WithStateChangeCallback registers function called on each circuit state change.
+func WithStateChangeCallback(value func(from, to CircuitState)) CircuitBreakerClientOption {
+	return func(client *CircuitBreakerClient) {
+		client.onStateChange = append(client.onStateChange, value)
+	}
+}
+func WithBreakerClock(value Clock) CircuitBreakerClientOption {
+	return func(client *CircuitBreakerClient) {
+		client.clock = value
+	}
+}
+func WithBreakerLogger(value logging.Logger) CircuitBreakerClientOption {
+	return func(client *CircuitBreakerClient) {
+		client.logger = value
+	}
+}
+
+// endregion
+// region - oauth2 client options
+
+type OAuth2ClientOption func(*OAuth2Client)
+
+// WithOAuth2ClientCredentials makes OAuth2Client obtain tokens with client credentials grant.
+func WithOAuth2ClientCredentials(tokenURL, clientID, clientSecret string, scopes ...string) OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.grantType = "client_credentials"
+		client.tokenURL = tokenURL
+		client.clientID = clientID
+		client.clientSecret = clientSecret
+		client.scopes = scopes
+	}
+}
+
+// WithOAuth2RefreshToken makes OAuth2Client obtain tokens with refresh token grant. If token
+// endpoint rotates refresh token, the new one is used for subsequent requests.
+func WithOAuth2RefreshToken(tokenURL, clientID, clientSecret, refreshToken string, scopes ...string) OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.grantType = "refresh_token"
+		client.tokenURL = tokenURL
+		client.clientID = clientID
+		client.clientSecret = clientSecret
+		client.refreshToken = refreshToken
+		client.scopes = scopes
+	}
+}
+
+// WithOAuth2Param adds token request parameter (e.g. "audience").
+func WithOAuth2Param(key, value string) OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.params.Add(key, value)
+	}
+}
+
+// WithOAuth2CredentialsInBody sends client credentials as token request parameters
+// instead of HTTP Basic authentication.
+func WithOAuth2CredentialsInBody() OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.credentialsBody = true
+	}
+}
+
+// WithOAuth2HttpClient sets http client used to call token endpoint.
+func WithOAuth2HttpClient(value *http.Client) OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.httpClient = value
+	}
+}
+
+// WithOAuth2ExpirySkew sets how long before expiry token is refreshed (30s by default).
+func WithOAuth2ExpirySkew(value time.Duration) OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.expirySkew = value
+	}
+}
+
+// WithOAuth2FetchTimeout limits token request duration (30s by default).
+func WithOAuth2FetchTimeout(value time.Duration) OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.fetchTimeout = value
+	}
+}
+func WithOAuth2Clock(value Clock) OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.clock = value
+	}
+}
+func WithOAuth2Logger(value logging.Logger) OAuth2ClientOption {
+	return func(client *OAuth2Client) {
+		client.logger = value
+	}
+}
+
+// endregion
+// region - hmac signer options
+
+type HMACSignerOption func(*HMACSigner)
+
+// WithHMACKeyID sets key identifier sent in "X-Key-Id" header (see WithHMACKeyIDHeader).
+func WithHMACKeyID(value string) HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.keyID = value
+	}
+}
+func WithHMACKeyIDHeader(value string) HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.keyIDHeader = value
+	}
+}
+
+// WithHMACSignatureHeader sets signature header name ("X-Signature" by default).
+func WithHMACSignatureHeader(value string) HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.signatureHeader = value
+	}
+}
+
+// WithHMACTimestampHeader sets timestamp header name ("X-Timestamp" by default); empty
+// name disables the header.
+func WithHMACTimestampHeader(value string) HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.timestampHeader = value
+	}
+}
+
+// WithHMACBodyHashHeader makes signer send hex encoded SHA-256 of body in given header.
+func WithHMACBodyHashHeader(value string) HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.bodyHashHeader = value
+	}
+}
+
+// WithHMACTimestamp sets timestamp format (Unix time in seconds by default).
+func WithHMACTimestamp(value func(time.Time) string) HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.timestamp = value
+	}
+}
+
+// WithHMACCanonical sets function building string to sign (DefaultHMACCanonical by default).
+func WithHMACCanonical(value func(HMACCanonicalRequest) string) HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.canonical = value
+	}
+}
+
+// WithHMACBase64 makes signer encode signature with base64 instead of hex.
+func WithHMACBase64() HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.encode = base64Encode
+	}
+}
+func WithHMACClock(value Clock) HMACSignerOption {
+	return func(signer *HMACSigner) {
+		signer.clock = value
+	}
+}
+
+// endregion
+// region - sigv4 signer options
+
+type SigV4SignerOption func(*SigV4Signer)
+
+// WithSigV4UnsignedPayload makes signer use "UNSIGNED-PAYLOAD" instead of body hash,
+// so that body is not read before sending (supported by S3).
+func WithSigV4UnsignedPayload() SigV4SignerOption {
+	return func(signer *SigV4Signer) {
+		signer.unsignedPayload = true
+	}
+}
+
+// WithSigV4ContentSHA256Header makes signer send payload hash in "X-Amz-Content-Sha256"
+// header (it's always sent for "s3" service).
+func WithSigV4ContentSHA256Header() SigV4SignerOption {
+	return func(signer *SigV4Signer) {
+		signer.contentHeader = true
+	}
+}
+
+// WithSigV4DisableDoubleEscaping makes signer escape request path once (as S3 requires).
+func WithSigV4DisableDoubleEscaping() SigV4SignerOption {
+	return func(signer *SigV4Signer) {
+		signer.disableDoubleEsc = true
+	}
+}
+func WithSigV4Clock(value Clock) SigV4SignerOption {
+	return func(signer *SigV4Signer) {
+		signer.clock = value
+	}
+}
+
 // endregion
 // region - retry client options
 
@@ -118,9 +526,45 @@ func WithMaxAttempts(value int) RetryClientOption {
 		client.maxAttempts = value
 	}
 }
+
+// WithRetryDelay sets constant delay between retry attempts.
 func WithRetryDelay(value time.Duration) RetryClientOption {
 	return func(client *RetryClient) {
-		client.delay = value
+		client.backoff = ConstantBackoff(value)
+	}
+}
+
+// WithBackoff sets policy to calculate delay between retry attempts.
+func WithBackoff(value BackoffPolicy) RetryClientOption {
+	return func(client *RetryClient) {
+		client.backoff = value
+	}
+}
+
+// WithRetryMaxDelay caps delay between retry attempts.
+func WithRetryMaxDelay(value time.Duration) RetryClientOption {
+	return func(client *RetryClient) {
+		client.maxDelay = value
+	}
+}
+
+// WithRetryMaxElapsed limits total time spent on retrying; retry is not attempted
+// if it would start after the budget is exhausted.
+func WithRetryMaxElapsed(value time.Duration) RetryClientOption {
+	return func(client *RetryClient) {
+		client.maxElapsed = value
+	}
+}
+
+// WithRetryPolicy sets policy to decide which failures are retried (see DefaultRetryPolicy).
+func WithRetryPolicy(value RetryPolicy) RetryClientOption {
+	return func(client *RetryClient) {
+		client.policy = value
+	}
+}
+func WithRetryClock(value Clock) RetryClientOption {
+	return func(client *RetryClient) {
+		client.clock = value
 	}
 }
 func WithRetryLogger(value logging.Logger) RetryClientOption {
@@ -163,15 +607,75 @@ type pathOption struct {
 
 func (q *pathOption) Apply(rb *requestBuilder) error {
 	rb.queryPath = &q.value
+	rb.template = false
 	return nil
 }
 
+// WithQueryPath sets raw request path (relative to base URL). If mentioned multiple
+// times, the last one wins.
 func WithQueryPath(value string) RequestOption {
 	return &pathOption{
 		value: value,
 	}
 }
 
+type pathTemplateOption struct {
+	value string
+}
+
+func (q *pathTemplateOption) Apply(rb *requestBuilder) error {
+	rb.queryPath = &q.value
+	rb.template = true
+	return nil
+}
+
+// WithPathTemplate sets request path with "{name}" placeholders, e.g. "/users/{id}". Each
+// placeholder must be filled with WithPathParam. Replaces path set with WithQueryPath.
+func WithPathTemplate(value string) RequestOption {
+	return &pathTemplateOption{
+		value: value,
+	}
+}
+
+type pathParamOption struct {
+	name  string
+	value string
+}
+
+func (q *pathParamOption) Apply(rb *requestBuilder) error {
+	if rb.pathParams == nil {
+		rb.pathParams = make(map[string]string)
+	}
+	rb.pathParams[q.name] = q.value
+	return nil
+}
+
+// WithPathParam sets value of path template placeholder. Value is percent-escaped
+// (including '/'), so it always stays a single path segment.
+func WithPathParam(name string, value any) RequestOption {
+	return &pathParamOption{
+		name:  name,
+		value: fmt.Sprint(value),
+	}
+}
+
+type pathSegmentsOption struct {
+	values []string
+}
+
+func (q *pathSegmentsOption) Apply(rb *requestBuilder) error {
+	rb.segments = append(rb.segments, q.values...)
+	return nil
+}
+
+// WithPath appends percent-escaped segments to request path. Can be mentioned multiple
+// times; all segments are joined with '/'.
+func WithPath(segments ...string) RequestOption {
+	return &pathSegmentsOption{
+		values: segments,
+	}
+}
+
 // endregion - path
 // region - request param
 
@@ -222,6 +726,51 @@ func WithQueryParamList(key string, values ...string) RequestOption {
 }
 
 // endregion - request param
+// region - query struct
+
+type queryOption struct {
+	value any
+}
+
+func (q *queryOption) Apply(rb *requestBuilder) error {
+	values, err := encodeQuery(q.value)
+	if err != nil {
+		return err
+	}
+	if rb.queryParams == nil {
+		rb.queryParams = url.Values{}
+	}
+	for k, vs := range values {
+		rb.queryParams[k] = append(rb.queryParams[k], vs...)
+	}
+	return nil
+}
+
+// WithQuery adds query parameters encoded from struct, map or url.Values. Struct fields
+// are encoded according to "query" tag:
+//
+//	Page    int       `query:"page,omitempty"`
+//	Tags    []string  `query:"tag"`                     // tag=a&tag=b
+//	IDs     []int     `query:"ids,comma"`               // ids=1,2
+//	Kinds   []string  `query:"kind,brackets"`           // kind[]=a&kind[]=b
+//	Sort    []string  `query:"sort,indexed"`            // sort[0]=a&sort[1]=b
+//	Since   time.Time `query:"since" layout:"2006-01-02"`
+//	Filter  Filter    `query:"filter"`                  // filter.name=x
+//	Range   Range     `query:"range,brackets"`          // range[from]=1
+//	Secret  string    `query:"-"`
+//
+// Fields without tag use field name; embedded structs are flattened. Nil pointers are
+// always omitted, zero values are omitted with "omitempty". Time is formatted with
+// "layout" tag (time.RFC3339 by default; "unix" and "unixmilli" give epoch time).
+// Values implementing encoding.TextMarshaler (with value or pointer receiver) are encoded
+// with it; []byte is encoded as string.
+func WithQuery(value any) RequestOption {
+	return &queryOption{
+		value: value,
+	}
+}
+
+// endregion - query struct
 // region - header
 
 type headerOption struct {
@@ -263,5 +812,110 @@ func WithBody(body any) RequestOption {
 }
 
 // endregion - request body
+// region - request codec
+
+type requestCodecOption struct {
+	codec Codec
+}
+
+func (q *requestCodecOption) Apply(rb *requestBuilder) error {
+	rb.codec = q.codec
+	return nil
+}
+
+// WithBodyCodec sets codec to encode request body with. It is also used to decode
+// response if there is no client codec registered for response content type.
+func WithBodyCodec(codec Codec) RequestOption {
+	return &requestCodecOption{
+		codec: codec,
+	}
+}
+
+// endregion - request codec
+// region - multipart
+
+type multipartOption struct {
+	part multipartPart
+}
+
+func (q *multipartOption) Apply(rb *requestBuilder) error {
+	rb.multipart = append(rb.multipart, q.part)
+	return nil
+}
+
+// WithMultipartField adds form field to multipart/form-data request body.
+func WithMultipartField(name, value string) RequestOption {
+	return &multipartOption{
+		part: multipartPart{
+			name:  name,
+			value: value,
+		},
+	}
+}
+
+// WithMultipartFile adds file part to multipart/form-data request body. File content is
+// streamed from reader when request is sent; reader is not closed by the library.
+func WithMultipartFile(name, filename string, reader io.Reader) RequestOption {
+	return WithMultipartFileType(name, filename, "application/octet-stream", reader)
+}
+
+// WithMultipartFileType is WithMultipartFile with explicit part content type.
+func WithMultipartFileType(name, filename, contentType string, reader io.Reader) RequestOption {
+	return &multipartOption{
+		part: multipartPart{
+			name:        name,
+			filename:    filename,
+			contentType: contentType,
+			reader:      reader,
+		},
+	}
+}
+
+// endregion - multipart
+// region - hooks
+
+type requestHooksOption struct {
+	value hooks
+}
+
+func (q *requestHooksOption) Apply(rb *requestBuilder) error {
+	rb.hooks = rb.hooks.merge(q.value)
+	return nil
+}
+
+// WithBeforeRequest adds hook called before request is sent (after client hooks).
+func WithBeforeRequest(hook BeforeRequestHook) RequestOption {
+	return &requestHooksOption{hooks{before: []BeforeRequestHook{hook}}}
+}
+
+// WithAfterResponse adds hook called for response before it's checked & decoded (after client hooks).
+func WithAfterResponse(hook AfterResponseHook) RequestOption {
+	return &requestHooksOption{hooks{after: []AfterResponseHook{hook}}}
+}
+
+// WithOnError adds hook called if request fails (after client hooks).
+func WithOnError(hook ErrorHook) RequestOption {
+	return &requestHooksOption{hooks{onError: []ErrorHook{hook}}}
+}
+
+// endregion - hooks
+// region - error result
+
+type errorResultOption struct {
+	value any
+}
+
+func (q *errorResultOption) Apply(rb *requestBuilder) error {
+	rb.errorResult = q.value
+	return nil
+}
+
+// WithErrorResult sets pointer non-2xx response body is decoded into. Decoded value is
+// also available as HTTPError.Result.
+func WithErrorResult(value any) RequestOption {
+	return &errorResultOption{value}
+}
+
+// endregion - error result
 
 // endregion
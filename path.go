@@ -0,0 +1,68 @@
+package rc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// expandPath replaces "{name}" placeholders of path template with escaped parameter
+// values. Every placeholder must be filled, and every parameter must be used.
+func expandPath(template string, params map[string]string) (string, error) {
+	var b strings.Builder
+	used := make(map[string]bool, len(params))
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			if strings.IndexByte(rest, '}') >= 0 {
+				return "", fmt.Errorf("%w: unexpected '}' in %q", ErrPathTemplate, template)
+			}
+			b.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("%w: unclosed '{' in %q", ErrPathTemplate, template)
+		}
+		name := rest[start+1 : start+end]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("%w: parameter %q of %q is not set", ErrPathTemplate, name, template)
+		}
+		used[name] = true
+		b.WriteString(rest[:start])
+		b.WriteString(url.PathEscape(value))
+		rest = rest[start+end+1:]
+	}
+	for name := range params {
+		if !used[name] {
+			return "", fmt.Errorf("%w: parameter %q is not used by %q", ErrPathTemplate, name, template)
+		}
+	}
+	return b.String(), nil
+}
+
+// joinPath appends escaped segments to path.
+func joinPath(path string, segments []string) string {
+	if len(segments) == 0 {
+		return path
+	}
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = url.PathEscape(s)
+	}
+	if path == "" {
+		return strings.Join(escaped, "/")
+	}
+	return strings.TrimSuffix(path, "/") + "/" + strings.Join(escaped, "/")
+}
+
+// RequestRoute returns route label of request built by NewRequest: path template (see
+// WithPathTemplate) followed by segments added with WithPath, or raw path set with
+// WithQueryPath. Unlike request path, it does not depend on path parameter values, so
+// it's suitable as metrics label or throttle key (ThrottleKeyFunc(RequestRoute)).
+func RequestRoute(req *http.Request) string {
+	return getRequestMeta(req).route
+}
@@ -0,0 +1,98 @@
+package rc
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ContentTypeProblemJSON is the content type of RFC 7807 (RFC 9457) problem details.
+const ContentTypeProblemJSON = "application/problem+json"
+
+// ProblemDetails is RFC 7807 (RFC 9457) error response. It's decoded automatically for
+// "application/problem+json" error responses and is available as HTTPError.Problem.
+// Members not defined by the RFC are collected into Extensions.
+type ProblemDetails struct {
+	Type       string         `json:"type,omitempty"`
+	Title      string         `json:"title,omitempty"`
+	Status     int            `json:"status,omitempty"`
+	Detail     string         `json:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	type plain ProblemDetails
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	var members map[string]any
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, k := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, k)
+	}
+	p.Extensions = nil
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+	return nil
+}
+
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	type plain ProblemDetails
+	data, err := json.Marshal(plain(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+	members := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	if err = json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// ErrorResult returns error response body decoded into value of type T (see WithErrorResult
+// and WithBasicErrorResult), if err wraps HTTPError holding such value.
+func ErrorResult[T any](err error) (T, bool) {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		if v, ok := he.Result.(T); ok {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// decodeError decodes body of error response into problem details and error result.
+// Decoding failures are not reported: caller gets HTTPError with raw body anyway.
+func (c *BasicClient) decodeError(e *HTTPError, meta *requestMeta) {
+	if len(e.Body) == 0 || e.Truncated {
+		return
+	}
+	contentType := e.Header.Get("Content-Type")
+	if mediaType(contentType) == ContentTypeProblemJSON {
+		problem := &ProblemDetails{}
+		if err := json.Unmarshal(e.Body, problem); err != nil {
+			c.logger.Debug("could not decode problem details: %s", err)
+		} else {
+			e.Problem = problem
+		}
+	}
+	result := meta.errorResult
+	if result == nil && c.errorResult != nil {
+		result = c.errorResult()
+	}
+	if result == nil {
+		return
+	}
+	if err := decodeBytes(e.Body, contentType, c.codecs, meta.codec, result); err != nil {
+		c.logger.Debug("could not decode error response: %s", err)
+		return
+	}
+	e.Result = result
+}
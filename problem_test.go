@@ -0,0 +1,85 @@
+package rc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testApiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func TestProblemDetails(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeProblemJSON)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"type":"https://example.com/invalid","title":"Invalid item","status":422,
+			"detail":"name is required","code":"E42","errors":["name"]}`))
+	})
+	_, _, err := Post[testItem](context.Background(), c, testItem{})
+	var he *HTTPError
+	if assert.ErrorAs(t, err, &he) && assert.NotNil(t, he.Problem) {
+		assert.Equal(t, "https://example.com/invalid", he.Problem.Type)
+		assert.Equal(t, "Invalid item", he.Problem.Title)
+		assert.Equal(t, http.StatusUnprocessableEntity, he.Problem.Status)
+		assert.Equal(t, "name is required", he.Problem.Detail)
+		assert.Equal(t, map[string]any{"code": "E42", "errors": []any{"name"}}, he.Problem.Extensions)
+		assert.Nil(t, he.Result)
+	}
+
+	data, err := json.Marshal(he.Problem)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"https://example.com/invalid","title":"Invalid item","status":422,
+		"detail":"name is required","code":"E42","errors":["name"]}`, string(data))
+}
+func TestErrorResult(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeProblemJSON)
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"title":"Conflict","code":"E409","message":"already exists"}`))
+	}
+
+	// per request
+	c := createTestServerClient(t, handler)
+	var apiErr testApiError
+	_, _, err := Post[testItem](context.Background(), c, testItem{}, WithErrorResult(&apiErr))
+	assert.Error(t, err)
+	assert.Equal(t, testApiError{Code: "E409", Message: "already exists"}, apiErr)
+	var he *HTTPError
+	if assert.ErrorAs(t, err, &he) {
+		assert.Equal(t, &apiErr, he.Result)
+		assert.Equal(t, "Conflict", he.Problem.Title)
+	}
+
+	// per client
+	c = createTestServerClient(t, handler,
+		WithBasicOption(WithBasicErrorResult(func() any { return &testApiError{} })),
+	)
+	_, _, err = Post[testItem](context.Background(), c, testItem{})
+	result, ok := ErrorResult[*testApiError](err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "E409", result.Code)
+	}
+	_, ok = ErrorResult[*ProblemDetails](err)
+	assert.False(t, ok)
+}
+func TestErrorResultNotDecodable(t *testing.T) {
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`<html>Bad Gateway</html>`))
+	})
+	var apiErr testApiError
+	_, _, err := Get[testItem](context.Background(), c, WithErrorResult(&apiErr))
+	var he *HTTPError
+	if assert.ErrorAs(t, err, &he) {
+		assert.Nil(t, he.Result)
+		assert.Nil(t, he.Problem)
+		assert.Equal(t, "<html>Bad Gateway</html>", string(he.Body))
+	}
+}
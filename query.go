@@ -0,0 +1,286 @@
+package rc
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type queryStyle int
+
+const (
+	queryRepeat queryStyle = iota
+	queryComma
+	queryBrackets
+	queryIndexed
+)
+
+type queryField struct {
+	name      string
+	omitEmpty bool
+	style     queryStyle
+	layout    string
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// encodeQuery encodes struct, map or url.Values into query parameters.
+func encodeQuery(v any) (url.Values, error) {
+	values := url.Values{}
+	switch v := v.(type) {
+	case nil:
+		return values, nil
+	case url.Values:
+		for k, vs := range v {
+			values[k] = append(values[k], vs...)
+		}
+		return values, nil
+	case map[string][]string:
+		return encodeQuery(url.Values(v))
+	}
+	rv := indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return values, nil
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return values, encodeQueryStruct(values, "", rv, false)
+	case reflect.Map:
+		return values, encodeQueryMap(values, "", rv, queryField{}, false)
+	}
+	return nil, fmt.Errorf("query: %w %T", ErrUnsupportedType, v)
+}
+
+func encodeQueryStruct(values url.Values, prefix string, rv reflect.Value, brackets bool) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup("query")
+		if tag == "-" {
+			continue
+		}
+		f := parseQueryTag(tag)
+		f.layout = sf.Tag.Get("layout")
+		fv := rv.Field(i)
+		if sf.Anonymous && f.name == "" {
+			if fv = indirect(fv); fv.IsValid() && fv.Kind() == reflect.Struct {
+				if err := encodeQueryStruct(values, prefix, fv, brackets); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if !tagged || f.name == "" {
+			f.name = sf.Name
+		}
+		if err := encodeQueryValue(values, queryKey(prefix, f.name, brackets), fv, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeQueryMap(values url.Values, prefix string, rv reflect.Value, f queryField, brackets bool) error {
+	if rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("query: %w map key %s", ErrUnsupportedType, rv.Type().Key())
+	}
+	iter := rv.MapRange()
+	for iter.Next() {
+		leaf := f
+		leaf.omitEmpty = false
+		if err := encodeQueryValue(values, queryKey(prefix, iter.Key().String(), brackets), iter.Value(), leaf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeQueryValue(values url.Values, key string, rv reflect.Value, f queryField) error {
+	rv = indirect(rv)
+	if !rv.IsValid() {
+		return nil // nil pointer or interface
+	}
+	if f.omitEmpty && isEmptyValue(rv) {
+		return nil
+	}
+	if s, ok, err := formatQueryScalar(rv, f); ok || err != nil {
+		if err == nil {
+			values.Add(key, s)
+		}
+		return err
+	}
+	brackets := f.style == queryBrackets || f.style == queryIndexed
+	switch rv.Kind() {
+	case reflect.Struct:
+		return encodeQueryStruct(values, key, rv, brackets)
+	case reflect.Map:
+		return encodeQueryMap(values, key, rv, f, brackets)
+	case reflect.Slice, reflect.Array:
+		return encodeQueryList(values, key, rv, f)
+	}
+	return fmt.Errorf("query: %w %s", ErrUnsupportedType, rv.Type())
+}
+
+func encodeQueryList(values url.Values, key string, rv reflect.Value, f queryField) error {
+	leaf := f
+	leaf.omitEmpty = false
+	leaf.style = queryRepeat
+	if f.style == queryIndexed {
+		// nested values of elements use brackets too: a[0][b]
+		leaf.style = queryBrackets
+		for i := 0; i < rv.Len(); i++ {
+			if err := encodeQueryValue(values, key+"["+strconv.Itoa(i)+"]", rv.Index(i), leaf); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	items := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		item := indirect(rv.Index(i))
+		if !item.IsValid() {
+			continue
+		}
+		s, ok, err := formatQueryScalar(item, leaf)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("query: %w %s in %q (use indexed style)", ErrUnsupportedType, item.Type(), key)
+		}
+		items = append(items, s)
+	}
+	switch f.style {
+	case queryComma:
+		if len(items) > 0 {
+			values.Add(key, strings.Join(items, ","))
+		}
+	case queryBrackets:
+		values[key+"[]"] = append(values[key+"[]"], items...)
+	default:
+		values[key] = append(values[key], items...)
+	}
+	return nil
+}
+
+// formatQueryScalar formats value which is represented by a single string.
+func formatQueryScalar(rv reflect.Value, f queryField) (string, bool, error) {
+	// fields of unexported embedded structs can't be converted to interface
+	if rv.CanInterface() {
+		if s, ok, err := formatQueryValue(rv, f); ok || err != nil {
+			return s, ok, err
+		}
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), true, nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes()), true, nil // []byte is a string, not a list of numbers
+		}
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()), true, nil
+	}
+	return "", false, nil
+}
+
+// formatQueryValue formats time, duration and encoding.TextMarshaler values (including
+// those with pointer receiver).
+func formatQueryValue(rv reflect.Value, f queryField) (string, bool, error) {
+	switch rv.Type() {
+	case timeType:
+		t := rv.Interface().(time.Time)
+		switch f.layout {
+		case "":
+			return t.Format(time.RFC3339), true, nil
+		case "unix":
+			return strconv.FormatInt(t.Unix(), 10), true, nil
+		case "unixmilli":
+			return strconv.FormatInt(t.UnixMilli(), 10), true, nil
+		}
+		return t.Format(f.layout), true, nil
+	case durationType:
+		return rv.Interface().(time.Duration).String(), true, nil
+	}
+	if rv.Type().Implements(textMarshalerType) {
+		b, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), true, err
+	}
+	if reflect.PointerTo(rv.Type()).Implements(textMarshalerType) {
+		// MarshalText has pointer receiver; take address of value (or of its copy)
+		pv := reflect.New(rv.Type())
+		if rv.CanAddr() {
+			pv = rv.Addr()
+		} else {
+			pv.Elem().Set(rv)
+		}
+		b, err := pv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), true, err
+	}
+	return "", false, nil
+}
+
+func parseQueryTag(tag string) queryField {
+	name, opts, _ := strings.Cut(tag, ",")
+	f := queryField{name: name}
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		switch opt {
+		case "omitempty":
+			f.omitEmpty = true
+		case "comma":
+			f.style = queryComma
+		case "brackets":
+			f.style = queryBrackets
+		case "indexed":
+			f.style = queryIndexed
+		}
+	}
+	return f
+}
+
+func queryKey(prefix, name string, brackets bool) string {
+	switch {
+	case prefix == "":
+		return name
+	case brackets:
+		return prefix + "[" + name + "]"
+	}
+	return prefix + "." + name
+}
+
+func indirect(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+func isEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
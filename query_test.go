@@ -0,0 +1,151 @@
+package rc
+
+import (
+	"bytes"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testQueryRange struct {
+	From int `query:"from"`
+	To   int `query:"to,omitempty"`
+}
+
+type testQueryPaging struct {
+	Page  int `query:"page,omitempty"`
+	Limit int `query:"limit,omitempty"`
+}
+
+type testQuerySort struct {
+	Field string `query:"field"`
+	Desc  bool   `query:"desc"`
+}
+
+type testQuery struct {
+	testQueryPaging
+	Query    string            `query:"q"`
+	Active   *bool             `query:"active"`
+	Score    float64           `query:"score,omitempty"`
+	Tags     []string          `query:"tag"`
+	IDs      []int             `query:"ids,comma"`
+	Kinds    []string          `query:"kind,brackets"`
+	Sort     []testQuerySort   `query:"sort,indexed"`
+	Since    time.Time         `query:"since" layout:"2006-01-02"`
+	Until    time.Time         `query:"until,omitempty" layout:"unix"`
+	Created  time.Time         `query:"created"`
+	Timeout  time.Duration     `query:"timeout,omitempty"`
+	Filter   testQueryRange    `query:"filter"`
+	Range    *testQueryRange   `query:"range,brackets"`
+	Missing  *testQueryRange   `query:"missing"`
+	Labels   map[string]string `query:"label,brackets"`
+	Secret   string            `query:"-"`
+	Untagged string
+	hidden   string
+}
+
+func TestEncodeQuery(t *testing.T) {
+	active := false
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	values, err := encodeQuery(&testQuery{
+		testQueryPaging: testQueryPaging{Page: 2},
+		Query:           "a b",
+		Active:          &active,
+		Tags:            []string{"x", "y"},
+		IDs:             []int{1, 2, 3},
+		Kinds:           []string{"k1", "k2"},
+		Sort:            []testQuerySort{{"name", false}, {"date", true}},
+		Since:           created,
+		Created:         created,
+		Timeout:         1500 * time.Millisecond,
+		Filter:          testQueryRange{From: 1},
+		Range:           &testQueryRange{From: 5, To: 10},
+		Labels:          map[string]string{"env": "prod"},
+		Secret:          "secret",
+		Untagged:        "u",
+		hidden:          "hidden",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{
+		"page":           {"2"},
+		"q":              {"a b"},
+		"active":         {"false"},
+		"tag":            {"x", "y"},
+		"ids":            {"1,2,3"},
+		"kind[]":         {"k1", "k2"},
+		"sort[0][field]": {"name"},
+		"sort[0][desc]":  {"false"},
+		"sort[1][field]": {"date"},
+		"sort[1][desc]":  {"true"},
+		"since":          {"2024-01-02"},
+		"created":        {"2024-01-02T03:04:05Z"},
+		"timeout":        {"1.5s"},
+		"filter.from":    {"1"},
+		"range[from]":    {"5"},
+		"range[to]":      {"10"},
+		"label[env]":     {"prod"},
+		"Untagged":       {"u"},
+	}, values)
+}
+
+type testQueryID struct {
+	value int
+}
+
+func (id *testQueryID) MarshalText() ([]byte, error) {
+	return []byte("id-" + strconv.Itoa(id.value)), nil
+}
+
+func TestEncodeQueryPointerMarshaler(t *testing.T) {
+	type query struct {
+		ID   testQueryID   `query:"id"`
+		IDs  []testQueryID `query:"ids,comma"`
+		Raw  []byte        `query:"raw"`
+		Skip []byte        `query:"skip,omitempty"`
+	}
+	v := query{ID: testQueryID{1}, IDs: []testQueryID{{2}, {3}}, Raw: []byte("ab")}
+	expected := url.Values{"id": {"id-1"}, "ids": {"id-2,id-3"}, "raw": {"ab"}}
+	values, err := encodeQuery(v)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, values)
+	values, err = encodeQuery(&v)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, values)
+}
+func TestEncodeQueryMaps(t *testing.T) {
+	values, err := encodeQuery(map[string]any{"a": 1, "b": []string{"x", "y"}, "c": nil})
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{"a": {"1"}, "b": {"x", "y"}}, values)
+
+	values, err = encodeQuery(url.Values{"a": {"1", "2"}})
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{"a": {"1", "2"}}, values)
+
+	_, err = encodeQuery(42)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	_, err = encodeQuery(struct {
+		Sort []testQuerySort `query:"sort"`
+	}{Sort: []testQuerySort{{}}})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+func TestCreteRequestWithQuery(t *testing.T) {
+	req, err := createTestClient().NewRequest(
+		WithQueryPath("/search"),
+		WithQueryParam("k", "v"),
+		WithQuery(testQueryPaging{Page: 3, Limit: 50}),
+		WithQuery(map[string]int{"k": 1}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://test.com/search?k=v&k=1&limit=50&page=3", req.URL.String())
+
+	_, err = createTestClient().NewRequest(WithQuery("bad"))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+func TestFormCodecStruct(t *testing.T) {
+	b := &bytes.Buffer{}
+	assert.NoError(t, FormCodec().Encode(b, testQueryRange{From: 1, To: 2}))
+	assert.Equal(t, "from=1&to=2", b.String())
+}
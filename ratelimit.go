@@ -0,0 +1,169 @@
+package rc
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// values above are treated as unix timestamps rather than delta-seconds
+const epochThreshold = 1_000_000_000
+
+var resetHeaders = []string{"X-RateLimit-Reset", "RateLimit-Reset"}
+
+// retryAfter extracts delay suggested by server from "Retry-After" header (delta-seconds
+// or HTTP-date) or, if it is absent, from common rate limit reset headers.
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return nonNegative(time.Duration(secs) * time.Second), true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return nonNegative(t.Sub(now)), true
+		}
+	}
+	for _, name := range resetHeaders {
+		if d, ok := parseReset(h.Get(name), now); ok {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseReset parses rate limit reset value, which is either delta-seconds or (GitHub style)
+// unix timestamp in seconds. Fractional values are accepted.
+func parseReset(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, false
+	}
+	if f >= epochThreshold {
+		sec := int64(f)
+		nsec := int64((f - float64(sec)) * float64(time.Second))
+		return nonNegative(time.Unix(sec, nsec).Sub(now)), true
+	}
+	return saturate(f * float64(time.Second)), true
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// region - rate limit state
+
+// RateLimitHeaders names response headers carrying upstream rate limit state.
+// Reset is either delta-seconds or unix timestamp.
+type RateLimitHeaders struct {
+	Limit     string
+	Remaining string
+	Reset     string
+}
+
+// DefaultRateLimitHeaders returns widely used "X-RateLimit-*" and IETF draft "RateLimit-*" header sets.
+func DefaultRateLimitHeaders() []RateLimitHeaders {
+	return []RateLimitHeaders{
+		{Limit: "X-RateLimit-Limit", Remaining: "X-RateLimit-Remaining", Reset: "X-RateLimit-Reset"},
+		{Limit: "RateLimit-Limit", Remaining: "RateLimit-Remaining", Reset: "RateLimit-Reset"},
+	}
+}
+
+// rateLimitState is upstream rate limit state reported by response; -1 means unknown.
+type rateLimitState struct {
+	limit     int
+	remaining int
+	reset     time.Duration
+}
+
+// parseRateLimit reads rate limit state from the first matching header set, falling back
+// to IETF structured "RateLimit" header (both "limit=10, remaining=5, reset=30" and
+// `"policy";r=5;t=30` forms). Returns false if response carries no rate limit info.
+func parseRateLimit(h http.Header, sets []RateLimitHeaders, now time.Time) (rateLimitState, bool) {
+	for _, set := range sets {
+		state := rateLimitState{
+			limit:     parseCount(h.Get(set.Limit)),
+			remaining: parseCount(h.Get(set.Remaining)),
+			reset:     -1,
+		}
+		if d, ok := parseReset(h.Get(set.Reset), now); ok {
+			state.reset = d
+		}
+		if state.limit >= 0 || state.remaining >= 0 {
+			return state, true
+		}
+	}
+	if v := h.Get("RateLimit"); v != "" {
+		return parseStructuredRateLimit(v)
+	}
+	return rateLimitState{}, false
+}
+
+func parseStructuredRateLimit(v string) (rateLimitState, bool) {
+	state := rateLimitState{limit: -1, remaining: -1, reset: -1}
+	found := false
+	for _, item := range strings.Split(v, ",") {
+		for _, param := range strings.Split(item, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok {
+				continue
+			}
+			n := parseCount(value)
+			if n < 0 {
+				continue
+			}
+			switch strings.ToLower(key) {
+			case "limit", "l", "q":
+				state.limit = n
+			case "remaining", "r":
+				// several policies may be reported; the most restrictive one matters
+				if state.remaining < 0 || n < state.remaining {
+					state.remaining = n
+				}
+			case "reset", "t":
+				if d := time.Duration(n) * time.Second; d > state.reset {
+					state.reset = d
+				}
+			default:
+				continue
+			}
+			found = true
+		}
+	}
+	return state, found
+}
+
+func parseCount(v string) int {
+	n, err := strconv.Atoi(strings.Trim(strings.TrimSpace(v), `"`))
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+// endregion
+
+// serverDelay returns delay requested by server with 429 or 503 response (or time left
+// till open circuit lets requests through), if any.
+func serverDelay(err error) (time.Duration, bool) {
+	var tmr ErrTooManyRequests
+	if errors.As(err, &tmr) && tmr.Delay > 0 {
+		return tmr.Delay, true
+	}
+	var su ErrServiceUnavailable
+	if errors.As(err, &su) && su.Delay > 0 {
+		return su.Delay, true
+	}
+	var co ErrCircuitOpen
+	if errors.As(err, &co) && co.Delay > 0 {
+		return co.Delay, true
+	}
+	return 0, false
+}
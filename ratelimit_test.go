@@ -0,0 +1,152 @@
+package rc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingClock fires timers immediately and records requested durations
+type recordingClock struct {
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (c *recordingClock) Now() time.Time {
+	return time.Now()
+}
+func (c *recordingClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+func (c *recordingClock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
+func TestRetryAfterParse(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		delay  time.Duration
+		ok     bool
+	}{
+		{"delta seconds", "Retry-After", "120", 2 * time.Minute, true},
+		{"http date", "Retry-After", "Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"http date in past", "Retry-After", "Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"invalid", "Retry-After", "soon", 0, false},
+		{"x-ratelimit epoch", "X-RateLimit-Reset", "1704110445", 45 * time.Second, true},
+		{"x-ratelimit delta", "X-RateLimit-Reset", "15", 15 * time.Second, true},
+		{"ratelimit delta", "RateLimit-Reset", "1.5", 1500 * time.Millisecond, true},
+		{"none", "X-Other", "1", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(tt.header, tt.value)
+			d, ok := retryAfter(h, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.delay, d)
+		})
+	}
+}
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	handler, calls := failingHandler(1, http.StatusTooManyRequests, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unavailable" {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler = func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			next(w, r)
+		}
+	}(handler)
+	clock := &recordingClock{}
+	c := createTestServerClient(t, handler,
+		WithRetryOption(WithMaxAttempts(2)),
+		WithRetryOption(WithRetryDelay(time.Millisecond)),
+		WithRetryOption(WithRetryClock(clock)),
+	)
+	_, _, err := Get[testItem](context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, []time.Duration{7 * time.Second}, clock.Waits())
+
+	_, res, err := Get[testItem](context.Background(), c, WithQueryPath("/unavailable"))
+	assert.ErrorAs(t, err, &ErrServiceUnavailable{})
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, 3*time.Second, clock.Waits()[1])
+}
+func TestRateLimitParse(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		state   rateLimitState
+		ok      bool
+	}{
+		{"x-ratelimit", map[string]string{"X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "7", "X-RateLimit-Reset": "1704110460"},
+			rateLimitState{limit: 100, remaining: 7, reset: time.Minute}, true},
+		{"ratelimit fields", map[string]string{"RateLimit-Remaining": "3", "RateLimit-Reset": "10"},
+			rateLimitState{limit: -1, remaining: 3, reset: 10 * time.Second}, true},
+		{"ietf list", map[string]string{"RateLimit": "limit=10, remaining=4, reset=30"},
+			rateLimitState{limit: 10, remaining: 4, reset: 30 * time.Second}, true},
+		{"ietf structured", map[string]string{"RateLimit": `"burst";r=9;t=1, "daily";r=2;t=3600`},
+			rateLimitState{limit: -1, remaining: 2, reset: time.Hour}, true},
+		{"none", map[string]string{"X-Other": "1"}, rateLimitState{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			state, ok := parseRateLimit(h, DefaultRateLimitHeaders(), now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.state, state)
+		})
+	}
+}
+func TestThrottleAdaptive(t *testing.T) {
+	clock := newManualClock()
+	var remaining atomic.Int32
+	remaining.Store(3)
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Quota-Left", strconv.Itoa(int(remaining.Add(-1))))
+		w.Header().Set("X-Quota-Reset", "300")
+	},
+		WithThrottleOption(WithMaxTokens(100)),
+		WithThrottleOption(WithRefillInterval(time.Minute)),
+		WithThrottleOption(WithThrottleClock(clock)),
+		WithThrottleOption(WithAdaptiveThrottle(RateLimitHeaders{Remaining: "X-Quota-Left", Reset: "X-Quota-Reset"})),
+	)
+	for i := 0; i < 3; i++ {
+		_, _, err := Get[testItem](context.Background(), c)
+		assert.NoError(t, err)
+	}
+	_, _, err := Get[testItem](context.Background(), c)
+	var tmr ErrTooManyRequests
+	if assert.ErrorAs(t, err, &tmr) {
+		assert.Equal(t, 5*time.Minute+100*time.Millisecond, tmr.Delay)
+	}
+	clock.Advance(5 * time.Minute)
+	_, _, err = Get[testItem](context.Background(), c)
+	assert.NoError(t, err)
+}
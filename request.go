@@ -2,7 +2,7 @@ package rc
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,19 +14,50 @@ type requestBuilder struct {
 	method      string
 	baseUrl     *url.URL
 	queryPath   *string
+	template    bool
+	pathParams  map[string]string
+	segments    []string
 	queryParams url.Values
 	headers     http.Header
 	body        any
+	multipart   []multipartPart
+	codec       Codec
+	hooks       hooks
+	errorResult any
 	userAgent   *string
 }
 
+// requestMeta holds per-request settings that are needed after request is built
+// (i.e. during execution & response processing). It travels with request context.
+type requestMeta struct {
+	codec       Codec
+	hooks       hooks
+	errorResult any
+	route       string
+}
+
+type requestMetaKey struct{}
+
+func getRequestMeta(req *http.Request) *requestMeta {
+	if req == nil {
+		return &requestMeta{}
+	}
+	if m, ok := req.Context().Value(requestMetaKey{}).(*requestMeta); ok {
+		return m
+	}
+	return &requestMeta{}
+}
+
 func (rb *requestBuilder) build() (*http.Request, error) {
 
 	var u *url.URL
-	var err error
 
-	if rb.queryPath != nil {
-		u, err = rb.baseUrl.Parse(strings.TrimPrefix(*rb.queryPath, "/"))
+	p, route, err := rb.path()
+	if err != nil {
+		return nil, err
+	}
+	if p != "" {
+		u, err = rb.baseUrl.Parse(p)
 		if err != nil {
 			return nil, fmt.Errorf("could not parse URL: %w", err)
 		}
@@ -35,15 +66,35 @@ func (rb *requestBuilder) build() (*http.Request, error) {
 	}
 	//fmt.Println("<<<<<<<", u)
 
-	var buf io.ReadWriter
+	if rb.codec == nil {
+		rb.codec = JSONCodec()
+	}
+
+	if rb.body != nil && len(rb.multipart) > 0 {
+		return nil, ErrBodyConflict
+	}
+
+	var buf io.Reader
+	var getBody func() (io.ReadCloser, error)
+	var contentType string
 	if rb.body != nil {
-		buf = &bytes.Buffer{}
-		enc := json.NewEncoder(buf)
-		enc.SetEscapeHTML(false)
-		err := enc.Encode(rb.body)
+		b := &bytes.Buffer{}
+		err := rb.codec.Encode(b, rb.body)
 		if err != nil {
 			return nil, fmt.Errorf("could not serialize body: %w", err)
 		}
+		data := b.Bytes()
+		buf = bytes.NewReader(data)
+		getBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+		contentType = rb.codec.ContentType()
+	}
+	if len(rb.multipart) > 0 {
+		mb := newMultipartBody(rb.multipart)
+		buf = mb
+		getBody = mb.replayer()
+		contentType = mb.ContentType()
 	}
 
 	urlStr := u.String()
@@ -53,13 +104,21 @@ func (rb *requestBuilder) build() (*http.Request, error) {
 
 	//fmt.Printf(">>>>> URL STR: %s\n", urlStr)
 
-	req, err := http.NewRequest(rb.method, urlStr, buf)
+	ctx := context.WithValue(context.Background(), requestMetaKey{}, &requestMeta{
+		codec:       rb.codec,
+		hooks:       rb.hooks,
+		errorResult: rb.errorResult,
+		route:       route,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, rb.method, urlStr, buf)
 	if err != nil {
 		return nil, err
 	}
+	req.GetBody = getBody
 
-	if rb.body != nil {
-		req.Header.Set("Content-Type", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
 	}
 
 	if rb.userAgent != nil && len(*rb.userAgent) > 0 {
@@ -73,3 +132,44 @@ func (rb *requestBuilder) build() (*http.Request, error) {
 	return req, nil
 
 }
+
+// path returns request path (relative to base URL) and its route label.
+func (rb *requestBuilder) path() (string, string, error) {
+	var p string
+	if rb.queryPath != nil {
+		p = *rb.queryPath
+	}
+	route := joinPath(p, rb.segments)
+	if rb.template {
+		var err error
+		if p, err = expandPath(p, rb.pathParams); err != nil {
+			return "", "", err
+		}
+	} else if len(rb.pathParams) > 0 {
+		return "", "", fmt.Errorf("%w: path parameters are set without path template", ErrPathTemplate)
+	}
+	p = strings.TrimPrefix(joinPath(p, rb.segments), "/")
+	if first, _, _ := strings.Cut(p, "/"); strings.Contains(first, ":") {
+		// don't let first segment be parsed as URL scheme
+		p = "./" + p
+	}
+	return p, route, nil
+}
+
+// rewindRequest returns a copy of already sent request with fresh body, so it can be
+// sent once more. Returns ErrBodyNotReplayable if request body can't be re-created.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return r, nil
+	}
+	if req.GetBody == nil {
+		return nil, ErrBodyNotReplayable
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBodyNotReplayable, err)
+	}
+	r.Body = body
+	return r, nil
+}
@@ -92,3 +92,58 @@ func TestCreteRequestWithArrayParams(t *testing.T) {
 	assert.Equal(t, http.MethodHead, req.Method)
 	assert.Equal(t, "test-agent", req.Header.Get("User-Agent"))
 }
+func TestCreteRequestWithPathTemplate(t *testing.T) {
+	req, err := createTestClient().NewRequest(
+		WithPathTemplate("/users/{id}/orders/{orderId}"),
+		WithPathParam("id", "a/b c"),
+		WithPathParam("orderId", 42),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://test.com/users/a%2Fb%20c/orders/42", req.URL.String())
+	assert.Equal(t, "/users/{id}/orders/{orderId}", RequestRoute(req))
+}
+func TestCreteRequestWithPathTemplateErrors(t *testing.T) {
+	_, err := createTestClient().NewRequest(
+		WithPathTemplate("/users/{id}/orders/{orderId}"),
+		WithPathParam("id", 1),
+	)
+	assert.ErrorIs(t, err, ErrPathTemplate)
+	_, err = createTestClient().NewRequest(
+		WithPathTemplate("/users/{id}"),
+		WithPathParam("id", 1),
+		WithPathParam("ID", 1),
+	)
+	assert.ErrorIs(t, err, ErrPathTemplate)
+	_, err = createTestClient().NewRequest(
+		WithPathTemplate("/users/{id"),
+		WithPathParam("id", 1),
+	)
+	assert.ErrorIs(t, err, ErrPathTemplate)
+	_, err = createTestClient().NewRequest(
+		WithQueryPath("/users"),
+		WithPathParam("id", 1),
+	)
+	assert.ErrorIs(t, err, ErrPathTemplate)
+}
+func TestCreteRequestWithPathSegments(t *testing.T) {
+	req, err := createTestClient().NewRequest(
+		WithPath("endpoint"),
+		WithPath("sub", "a:b"),
+		WithPath("c/d"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://test.com/endpoint/sub/a:b/c%2Fd", req.URL.String())
+
+	req, err = createTestClient().NewRequest(
+		WithPathTemplate("/users/{id}/"),
+		WithPathParam("id", "x:1"),
+		WithPath("orders"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://test.com/users/x:1/orders", req.URL.String())
+	assert.Equal(t, "/users/{id}/orders", RequestRoute(req))
+
+	req, err = createTestClient().NewRequest(WithPath("x:1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://test.com/x:1", req.URL.String())
+}
@@ -1,7 +1,59 @@
 package rc
 
-import "net/http"
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
 
 type Response struct {
 	*http.Response
+	// Attempt is the number of attempt this response was received on (see AttemptFromContext)
+	Attempt int
+	codecs  *codecRegistry
+	codec   Codec
+}
+
+// decode reads response body into v and closes it. Codec is selected by response
+// Content-Type; if there is no codec registered for it, request codec is used.
+// If v is nil, response body is left untouched for the caller to consume.
+func (r *Response) decode(v interface{}) error {
+	var err error
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case io.Writer:
+		_, err = io.Copy(v, r.Body)
+	default:
+		var b []byte
+		b, err = io.ReadAll(r.Body)
+		if err != nil || len(b) == 0 {
+			break // nothing to decode in empty response body
+		}
+		err = decodeBytes(b, r.Header.Get("Content-Type"), r.codecs, r.codec, v)
+	}
+	clErr := r.Body.Close()
+	if err != nil {
+		return err
+	}
+	return clErr
+}
+
+// decodeBytes decodes b into v with codec registered for given content type. If there is
+// no such codec, or it does not support v, fallback codec (JSON if nil) is used.
+func decodeBytes(b []byte, contentType string, codecs *codecRegistry, fallback Codec, v interface{}) error {
+	if fallback == nil {
+		fallback = JSONCodec()
+	}
+	codec := codecs.lookup(contentType)
+	if codec == nil {
+		codec = fallback
+	}
+	err := codec.Decode(bytes.NewReader(b), v)
+	if errors.Is(err, ErrUnsupportedType) && codec != fallback {
+		// e.g. JSON sent as "text/plain" by server that relies on content sniffing
+		err = fallback.Decode(bytes.NewReader(b), v)
+	}
+	return err
 }
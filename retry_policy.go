@@ -0,0 +1,65 @@
+package rc
+
+import (
+	"errors"
+	"net/http"
+)
+
+// region - retry policy
+
+// RetryPolicy decides whether failed request should be retried. Attempt is the number
+// of attempts made so far (starting with 1); status is 0 if response was not received.
+type RetryPolicy interface {
+	ShouldRetry(req *http.Request, status int, err error, attempt int) bool
+}
+
+type RetryPolicyFunc func(req *http.Request, status int, err error, attempt int) bool
+
+func (f RetryPolicyFunc) ShouldRetry(req *http.Request, status int, err error, attempt int) bool {
+	return f(req, status, err, attempt)
+}
+
+// DefaultRetryPolicy retries requests that were not processed (429 responses, bulkhead
+// rejections) and, for idempotent requests only (see IsIdempotentRequest), 5xx responses
+// and network errors. ErrCircuitOpen is not retried, so that open circuit fails fast; custom
+// policy may retry it, waiting till circuit is half-open.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicyFunc(func(req *http.Request, status int, err error, _ int) bool {
+		if errors.As(err, &ErrTooManyRequests{}) || errors.As(err, &ErrBulkheadFull{}) {
+			return true
+		}
+		if errors.As(err, &ErrResourceNotFound{}) || errors.Is(err, ErrClientClosed) {
+			return false
+		}
+		if !IsIdempotentRequest(req) {
+			return false
+		}
+		return isTransportError(err) || status >= http.StatusInternalServerError
+	})
+}
+
+// endregion
+// region - util
+
+var idempotencyKeyHeaders = []string{"Idempotency-Key", "X-Idempotency-Key"}
+
+// IsIdempotentRequest reports whether request may be safely repeated: its method is idempotent
+// (RFC 9110) or it carries idempotency key header.
+func IsIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	for _, h := range idempotencyKeyHeaders {
+		if req.Header.Get(h) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isTransportError(err error) bool {
+	return errors.Is(err, ErrTransport)
+}
+
+// endregion
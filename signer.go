@@ -0,0 +1,175 @@
+package rc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// region - hmac signer
+
+// HMACCanonicalRequest holds request parts covered by HMAC signature. Path is escaped
+// request path, Query is sorted query string, BodyHash is hex encoded SHA-256 of body.
+type HMACCanonicalRequest struct {
+	Method    string
+	Path      string
+	Query     string
+	Timestamp string
+	BodyHash  string
+	Header    http.Header
+}
+
+// DefaultHMACCanonical joins method, path, query, timestamp and body hash with "\n".
+func DefaultHMACCanonical(r HMACCanonicalRequest) string {
+	return strings.Join([]string{r.Method, r.Path, r.Query, r.Timestamp, r.BodyHash}, "\n")
+}
+
+// HMACSigner signs requests with HMAC-SHA256. By default, signature of canonical request
+// (see DefaultHMACCanonical) is set as hex encoded "X-Signature" header, and Unix time
+// used for signing is set as "X-Timestamp" header. Sign is BeforeRequestHook, so signer
+// is added with WithBasicBeforeRequest(signer.Sign) (or WithBeforeRequest for single
+// request); request is signed on each attempt, so timestamp is always fresh.
+type HMACSigner struct {
+	key             []byte
+	keyID           string
+	keyIDHeader     string
+	signatureHeader string
+	timestampHeader string
+	bodyHashHeader  string
+	timestamp       func(time.Time) string
+	canonical       func(HMACCanonicalRequest) string
+	encode          func([]byte) string
+	clock           Clock
+}
+
+func NewHMACSigner(key []byte, options ...HMACSignerOption) *HMACSigner {
+	s := &HMACSigner{
+		key:             key,
+		keyIDHeader:     "X-Key-Id",
+		signatureHeader: "X-Signature",
+		timestampHeader: "X-Timestamp",
+		timestamp: func(t time.Time) string {
+			return strconv.FormatInt(t.Unix(), 10)
+		},
+		canonical: DefaultHMACCanonical,
+		encode:    hex.EncodeToString,
+		clock:     SystemClock(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Sign sets signature headers of request. Request body is read and restored.
+func (s *HMACSigner) Sign(_ context.Context, req *http.Request) error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return err
+	}
+	bodyHash := sha256Hex(body)
+	timestamp := s.timestamp(s.clock.Now())
+
+	if s.timestampHeader != "" {
+		req.Header.Set(s.timestampHeader, timestamp)
+	}
+	if s.bodyHashHeader != "" {
+		req.Header.Set(s.bodyHashHeader, bodyHash)
+	}
+	if s.keyID != "" {
+		req.Header.Set(s.keyIDHeader, s.keyID)
+	}
+
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(s.canonical(HMACCanonicalRequest{
+		Method:    req.Method,
+		Path:      req.URL.EscapedPath(),
+		Query:     canonicalQuery(req.URL),
+		Timestamp: timestamp,
+		BodyHash:  bodyHash,
+		Header:    req.Header,
+	})))
+	req.Header.Set(s.signatureHeader, s.encode(mac.Sum(nil)))
+	return nil
+}
+
+// endregion
+// region - util
+
+// readRequestBody reads request body and replaces it with in-memory copy, so that request
+// can still be sent. Streaming bodies (e.g. multipart) are buffered.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return body, nil
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalQuery returns query sorted by keys and values, with RFC 3986 escaping.
+func canonicalQuery(u *url.URL) string {
+	type pair struct{ k, v string }
+	var pairs []pair
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			pairs = append(pairs, pair{uriEncode(k, true), uriEncode(v, true)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].k != pairs[j].k {
+			return pairs[i].k < pairs[j].k
+		}
+		return pairs[i].v < pairs[j].v
+	})
+	items := make([]string, len(pairs))
+	for i, p := range pairs {
+		items[i] = p.k + "=" + p.v
+	}
+	return strings.Join(items, "&")
+}
+
+// uriEncode escapes everything but RFC 3986 unreserved characters ('/' is kept
+// unless encodeSlash is set).
+func uriEncode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&15])
+	}
+	return b.String()
+}
+
+func base64Encode(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// endregion
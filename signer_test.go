@@ -0,0 +1,84 @@
+package rc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalQuery(t *testing.T) {
+	u, _ := url.Parse("https://test.com/?b=2&a=2&a=1&a-b=x&c=a%20b+c&d=%2F~")
+	assert.Equal(t, "a=1&a=2&a-b=x&b=2&c=a%20b%20c&d=%2F~", canonicalQuery(u))
+}
+func TestHMACSigner(t *testing.T) {
+	key := []byte("secret")
+	clock := newManualClock()
+	var timestamps []string
+	handler, calls := failingHandler(1, http.StatusServiceUnavailable, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(strings.Join([]string{
+			r.Method, r.URL.EscapedPath(), "a=1&b=x%20y", r.Header.Get("X-Timestamp"), hex.EncodeToString(sum[:]),
+		}, "\n")))
+		assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Signature"))
+		assert.Equal(t, "k1", r.Header.Get("X-Key-Id"))
+		assert.Equal(t, hex.EncodeToString(sum[:]), r.Header.Get("X-Content-Sha256"))
+		_, _ = w.Write(body)
+	})
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		timestamps = append(timestamps, r.Header.Get("X-Timestamp"))
+		handler(w, r)
+	},
+		WithBasicOption(WithBasicBeforeRequest(NewHMACSigner(key,
+			WithHMACKeyID("k1"),
+			WithHMACBodyHashHeader("X-Content-Sha256"),
+			WithHMACClock(clock),
+		).Sign)),
+		WithRetryOption(WithMaxAttempts(2)),
+		WithRetryOption(WithRetryDelay(time.Minute)),
+		WithRetryOption(WithRetryClock(clock)),
+	)
+	item, _, err := Put[testItem](context.Background(), c, testItem{ID: 1, Name: "signed"},
+		WithPathTemplate("/items/{id}"), WithPathParam("id", "a b"),
+		WithQueryParam("b", "x y"), WithQueryParam("a", "1"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, testItem{ID: 1, Name: "signed"}, item)
+	assert.Equal(t, int32(2), calls.Load())
+	// re-signed after retry delay
+	assert.Equal(t, []string{"1704067200", "1704067260"}, timestamps)
+}
+func TestHMACSignerCustom(t *testing.T) {
+	key := []byte("secret")
+	c := createTestServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("Date")))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), r.Header.Get("Signature"))
+		assert.Empty(t, r.Header.Get("X-Timestamp"))
+	})
+	signer := NewHMACSigner(key,
+		WithHMACSignatureHeader("Signature"),
+		WithHMACTimestampHeader(""),
+		WithHMACTimestamp(func(t time.Time) string {
+			return t.UTC().Format(http.TimeFormat)
+		}),
+		WithHMACCanonical(func(r HMACCanonicalRequest) string {
+			r.Header.Set("Date", r.Timestamp)
+			return r.Method + " " + r.Path + " " + r.Timestamp
+		}),
+		WithHMACBase64(),
+	)
+	_, _, err := Get[string](context.Background(), c, WithQueryPath("/items"), WithBeforeRequest(signer.Sign))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,203 @@
+package rc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	sigV4Algorithm       = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat      = "20060102T150405Z"
+	sigV4DateFormat      = "20060102"
+	sigV4UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// sigV4IgnoredHeaders are not signed, as they may be changed on the way to server.
+var sigV4IgnoredHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+	"connection":      true,
+}
+
+// region - credentials
+
+// AWSCredentials are AWS access keys; SessionToken is set for temporary credentials.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSCredentialsProvider returns credentials to sign request with. It's called for each
+// request attempt, so it should cache credentials obtained from remote sources.
+type AWSCredentialsProvider interface {
+	Retrieve(ctx context.Context) (AWSCredentials, error)
+}
+
+type AWSCredentialsFunc func(ctx context.Context) (AWSCredentials, error)
+
+func (f AWSCredentialsFunc) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	return f(ctx)
+}
+
+// StaticAWSCredentials returns provider of fixed credentials.
+func StaticAWSCredentials(accessKeyID, secretAccessKey, sessionToken string) AWSCredentialsProvider {
+	return AWSCredentialsFunc(func(context.Context) (AWSCredentials, error) {
+		return AWSCredentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    sessionToken,
+		}, nil
+	})
+}
+
+// endregion
+// region - signer
+
+// SigV4Signer signs requests with AWS Signature Version 4. Like HMACSigner, it's used as
+// BeforeRequestHook: WithBasicBeforeRequest(signer.Sign); thus each retry attempt is signed
+// anew. For "s3" service, payload hash is sent in "X-Amz-Content-Sha256" header and path
+// is not escaped twice.
+type SigV4Signer struct {
+	service          string
+	region           string
+	credentials      AWSCredentialsProvider
+	unsignedPayload  bool
+	contentHeader    bool
+	disableDoubleEsc bool
+	clock            Clock
+}
+
+func NewSigV4Signer(service, region string, credentials AWSCredentialsProvider, options ...SigV4SignerOption) *SigV4Signer {
+	s := &SigV4Signer{
+		service:          service,
+		region:           region,
+		credentials:      credentials,
+		contentHeader:    service == "s3",
+		disableDoubleEsc: service == "s3",
+		clock:            SystemClock(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Sign sets "Authorization", "X-Amz-Date" and (if needed) "X-Amz-Security-Token" and
+// "X-Amz-Content-Sha256" headers. Request body is read and restored.
+func (s *SigV4Signer) Sign(ctx context.Context, req *http.Request) error {
+
+	credentials, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+
+	payloadHash := sigV4UnsignedPayload
+	if !s.unsignedPayload {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return err
+		}
+		payloadHash = sha256Hex(body)
+	}
+
+	now := s.clock.Now().UTC()
+	amzDate := now.Format(sigV4TimeFormat)
+	scope := strings.Join([]string{now.Format(sigV4DateFormat), s.region, s.service, "aws4_request"}, "/")
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	if s.contentHeader {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	signedHeaders, canonicalHeaders := s.canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(req),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), now.Format(sigV4DateFormat))
+	for _, part := range []string{s.region, s.service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", sigV4Algorithm+
+		" Credential="+credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+	return nil
+}
+
+func (s *SigV4Signer) canonicalURI(req *http.Request) string {
+	path := req.URL.EscapedPath()
+	if s.disableDoubleEsc {
+		path = req.URL.Path
+	}
+	if path == "" {
+		return "/"
+	}
+	return uriEncode(path, false)
+}
+
+// canonicalHeaders returns signed header names and canonical headers block.
+func (s *SigV4Signer) canonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for k, vs := range req.Header {
+		name := strings.ToLower(k)
+		if sigV4IgnoredHeaders[name] {
+			continue
+		}
+		trimmed := make([]string, len(vs))
+		for i, v := range vs {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		values[name] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(values[name])
+		b.WriteByte('\n')
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// endregion